@@ -0,0 +1,201 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// A FieldTypeError is returned by the typed field accessors of FirestoreDocument when the field exists but holds a different Firestore data type.
+// Expected and Actual are Firestore protojson type descriptor tags such as "stringValue" or "mapValue".
+type FieldTypeError struct {
+	Path     FieldPath
+	Expected string
+	Actual   string
+}
+
+func (e *FieldTypeError) Error() string {
+	return fmt.Sprintf("field %s is a %s, not a %s", e.Path, e.Actual, e.Expected)
+}
+
+// Exists reports whether the field at path, a Firestore field path such as "profile.name", exists in the document.
+// A field holding a Firestore null value exists.
+func (d *FirestoreDocument) Exists(path string) bool {
+	if d == nil {
+		return false
+	}
+
+	fp, err := ParseFieldPath(path)
+	if err != nil {
+		return false
+	}
+
+	_, err = lookupWrappedPath(d.Fields, fp)
+	return err == nil
+}
+
+// String returns the string stored at path, a Firestore field path such as "profile.name".
+// Like all typed accessors it only unwraps the requested field, an error wrapping ErrFieldNotFound is returned
+// if the field does not exist and a *FieldTypeError if it holds a different data type.
+func (d *FirestoreDocument) String(path string) (string, error) {
+	v, err := d.lookupTagged(path, protoStringTag)
+	if err != nil {
+		return "", err
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("field %s contains an invalid string value: %v", path, v)
+	}
+	return s, nil
+}
+
+// Reference returns the document reference stored at path.
+func (d *FirestoreDocument) Reference(path string) (string, error) {
+	v, err := d.lookupTagged(path, protoReferenceTag)
+	if err != nil {
+		return "", err
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("field %s contains an invalid reference value: %v", path, v)
+	}
+	return s, nil
+}
+
+// Bool returns the boolean stored at path.
+func (d *FirestoreDocument) Bool(path string) (bool, error) {
+	v, err := d.lookupTagged(path, protoBoolTag)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("field %s contains an invalid boolean value: %v", path, v)
+	}
+	return b, nil
+}
+
+// Int64 returns the integer stored at path.
+func (d *FirestoreDocument) Int64(path string) (int64, error) {
+	v, err := d.lookupTagged(path, protoIntTag)
+	if err != nil {
+		return 0, err
+	}
+
+	i, err := unwrapInt(v)
+	if err != nil {
+		return 0, err
+	}
+	return int64(i), nil
+}
+
+// Float64 returns the double stored at path.
+func (d *FirestoreDocument) Float64(path string) (float64, error) {
+	v, err := d.lookupTagged(path, protoDoubleTag)
+	if err != nil {
+		return 0, err
+	}
+
+	return unwrapDouble(v)
+}
+
+// Time returns the timestamp stored at path.
+func (d *FirestoreDocument) Time(path string) (time.Time, error) {
+	v, err := d.lookupTagged(path, protoTimestampTag)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return unwrapTimestamp(v)
+}
+
+// Bytes returns the bytes stored at path.
+func (d *FirestoreDocument) Bytes(path string) ([]byte, error) {
+	v, err := d.lookupTagged(path, protoBytesTag)
+	if err != nil {
+		return nil, err
+	}
+
+	return unwrapBytes(v)
+}
+
+// GeoPoint returns the geographical point stored at path.
+func (d *FirestoreDocument) GeoPoint(path string) (*latlng.LatLng, error) {
+	v, err := d.lookupTagged(path, protoGeoPointTag)
+	if err != nil {
+		return nil, err
+	}
+
+	gp, err := unwrapGeoPoint(v)
+	if err != nil {
+		return nil, err
+	}
+	return &latlng.LatLng{Latitude: gp.Latitude, Longitude: gp.Longitude}, nil
+}
+
+// Map returns the map stored at path, the map and all its nested values are unwrapped.
+func (d *FirestoreDocument) Map(path string) (map[string]any, error) {
+	v, err := d.lookupTagged(path, protoMapTag)
+	if err != nil {
+		return nil, err
+	}
+
+	return unwrapMap(v)
+}
+
+// Array returns the array stored at path, all its nested values are unwrapped.
+func (d *FirestoreDocument) Array(path string) ([]any, error) {
+	v, err := d.lookupTagged(path, protoArrayTag)
+	if err != nil {
+		return nil, err
+	}
+
+	return unwrapArray(v)
+}
+
+// lookupTagged returns the value wrapped by the protojson type descriptor tag of the field at path,
+// or a *FieldTypeError if the field is tagged with a different type.
+func (d *FirestoreDocument) lookupTagged(path string, tag string) (any, error) {
+	if d == nil {
+		return nil, errors.New("nil document contents")
+	}
+
+	fp, err := ParseFieldPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := lookupWrappedPath(d.Fields, fp)
+	if err != nil {
+		return nil, err
+	}
+
+	actual, inner, ok := wrappedTag(val)
+	if !ok {
+		return nil, fmt.Errorf("field %s is not a valid Firestore value: %v", fp, val)
+	}
+	if actual != tag {
+		return nil, &FieldTypeError{Path: fp, Expected: tag, Actual: actual}
+	}
+
+	return inner, nil
+}
@@ -0,0 +1,101 @@
+package firestruct
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bennovw/firestruct/internal/testutil"
+)
+
+func TestFirestoreDocumentAccessors(t *testing.T) {
+	thisMethodName := "FirestoreDocument accessors"
+	doc := FirestoreDocument{Fields: testutil.TestFirebaseDocFields[12]}
+	expected := testutil.FlattenedMapResults[12]
+
+	tests := []testutil.TableTest{
+		{Name: "string", TargetType: "String", Input: "stringData", Expected: expected["stringData"]},
+		{Name: "reference", TargetType: "Reference", Input: "referenceData", Expected: expected["referenceData"]},
+		{Name: "bool", TargetType: "Bool", Input: "boolData", Expected: expected["boolData"]},
+		{Name: "string encoded integer", TargetType: "Int64", Input: "intData", Expected: int64(987654321)},
+		{Name: "double", TargetType: "Float64", Input: "doubleData", Expected: expected["doubleData"]},
+		{Name: "timestamp", TargetType: "Time", Input: "timeData", Expected: expected["timeData"]},
+		{Name: "bytes", TargetType: "Bytes", Input: "bytesData", Expected: expected["bytesData"]},
+		{Name: "map", TargetType: "Map", Input: "nestedMapData", Expected: expected["nestedMapData"]},
+		{Name: "array nested in map", TargetType: "Array", Input: "nestedMapData.nestedArrayData", Expected: expected["nestedMapData"].(map[string]any)["nestedArrayData"]},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			path := test.Input.(string)
+
+			var result any
+			var err error
+			switch test.TargetType {
+			case "String":
+				result, err = doc.String(path)
+			case "Reference":
+				result, err = doc.Reference(path)
+			case "Bool":
+				result, err = doc.Bool(path)
+			case "Int64":
+				result, err = doc.Int64(path)
+			case "Float64":
+				result, err = doc.Float64(path)
+			case "Time":
+				result, err = doc.Time(path)
+			case "Bytes":
+				result, err = doc.Bytes(path)
+			case "Map":
+				result, err = doc.Map(path)
+			case "Array":
+				result, err = doc.Array(path)
+			default:
+				t.Errorf("%v() test \"%v\" method not covered", thisMethodName, test.Name)
+			}
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisMethodName, test.Name, err)
+			}
+			if !reflect.DeepEqual(result, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data: %v", thisMethodName, test.Name, result)
+			}
+		})
+	}
+
+	gp, err := doc.GeoPoint("geoPointData")
+	if err != nil {
+		t.Errorf("%v() test \"geopoint\" returned error: %v", thisMethodName, err)
+	} else if gp.Latitude != 51.205005708080876 || gp.Longitude != 3.225345050850536 {
+		t.Errorf("%v() test \"geopoint\" output does not match expected data: %v", thisMethodName, gp)
+	}
+}
+
+func TestFirestoreDocumentAccessorErrors(t *testing.T) {
+	doc := FirestoreDocument{Fields: testutil.TestFirebaseDocFields[12]}
+
+	if !doc.Exists("nilData") || !doc.Exists("nestedMapData.nestedArrayData") {
+		t.Errorf("Exists() did not find an existing field")
+	}
+	if doc.Exists("missing") || doc.Exists("stringData.nested") || doc.Exists("a..b") {
+		t.Errorf("Exists() found a missing field")
+	}
+
+	_, err := doc.Int64("stringData")
+	var typeErr *FieldTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Int64() expected a *FieldTypeError, got %v", err)
+	}
+	if typeErr.Expected != protoIntTag || typeErr.Actual != protoStringTag || typeErr.Path.String() != "stringData" {
+		t.Errorf("Int64() returned an unexpected type error: %v", typeErr)
+	}
+
+	if _, err := doc.String("nilData"); !errors.As(err, &typeErr) {
+		t.Errorf("String() on a null value expected a *FieldTypeError, got %v", err)
+	}
+	if _, err := doc.Time("nestedMapData.missing"); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Time() expected ErrFieldNotFound, got %v", err)
+	}
+	if _, err := doc.Map("a..b"); err == nil {
+		t.Errorf("Map() expected an error for an invalid field path")
+	}
+}
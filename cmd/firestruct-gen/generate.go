@@ -0,0 +1,599 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bennovw/firestruct/internal/fields"
+)
+
+// generatedHeader marks the files written by firestruct-gen, they are ignored when the package is loaded.
+const generatedHeader = "// Code generated by firestruct-gen. DO NOT EDIT."
+
+const firestructPath = "github.com/bennovw/firestruct"
+
+// loadPackage parses and type-checks the Go package in dir, ignoring test files and files written by firestruct-gen.
+func loadPackage(dir string) (*types.Package, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if len(f.Comments) > 0 && strings.HasPrefix(f.Comments[0].Text(), strings.TrimPrefix(generatedHeader, "// ")) {
+			continue
+		}
+		files = append(files, f)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	return conf.Check(bp.ImportPath, fset, files, nil)
+}
+
+// A generator writes the decoders of struct types of a package.
+type generator struct {
+	pkg      *types.Package
+	decoders map[*types.TypeName]bool // types a decoder is generated for
+	imports  map[string]string        // packages referenced by the generated code, by path
+	buf      bytes.Buffer
+}
+
+// generate returns the formatted source of the decoders of the named struct types of pkg.
+func generate(pkg *types.Package, names []string) ([]byte, error) {
+	g := &generator{
+		pkg:      pkg,
+		decoders: make(map[*types.TypeName]bool),
+		imports:  make(map[string]string),
+	}
+
+	var named []*types.Named
+	for _, name := range names {
+		obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok {
+			return nil, fmt.Errorf("type %s not found in package %s", name, pkg.Path())
+		}
+		t, ok := obj.Type().(*types.Named)
+		if !ok || t.TypeParams().Len() > 0 {
+			return nil, fmt.Errorf("type %s is not a defined non-generic type", name)
+		}
+		if _, ok := t.Underlying().(*types.Struct); !ok {
+			return nil, fmt.Errorf("type %s is not a struct", name)
+		}
+		g.decoders[obj] = true
+		named = append(named, t)
+	}
+
+	for _, t := range named {
+		if err := g.decoder(t); err != nil {
+			return nil, err
+		}
+	}
+	if err := g.checkEmbedded(); err != nil {
+		return nil, err
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "%s\n\npackage %s\n\nimport (\n", generatedHeader, pkg.Name())
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	// Standard library packages first, the paths of other packages start with a domain name
+	sort.Slice(paths, func(i, j int) bool {
+		if si, sj := isStdPath(paths[i]), isStdPath(paths[j]); si != sj {
+			return si
+		}
+		return paths[i] < paths[j]
+	})
+	for i, path := range paths {
+		if i > 0 && isStdPath(path) != isStdPath(paths[i-1]) {
+			src.WriteString("\n")
+		}
+		if name := g.imports[path]; name != filepath.Base(path) {
+			fmt.Fprintf(&src, "\t%s %q\n", name, path)
+			continue
+		}
+		fmt.Fprintf(&src, "\t%q\n", path)
+	}
+	src.WriteString(")\n")
+	src.Write(g.buf.Bytes())
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v", err)
+	}
+	return out, nil
+}
+
+// decoder writes the DecodeFirestore method of struct type t.
+func (g *generator) decoder(t *types.Named) error {
+	name := t.Obj().Name()
+	if _, ok := lookupMethod(t, "Defaults"); ok {
+		return fmt.Errorf("%s implements Defaulter, default values are not supported", name)
+	}
+
+	fs, err := structFields(t)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	for _, f := range fs {
+		switch {
+		case f.opts.remain:
+			return fmt.Errorf("%s.%s: the remain option is not supported", name, f.name)
+		case f.opts.hasDefault:
+			return fmt.Errorf("%s.%s: the default option is not supported", name, f.name)
+		}
+	}
+
+	names := "firestoreFields" + name
+	fmt.Fprintf(&g.buf, "\n// %s holds the Firestore field names of the fields of %s, in field order.\n", names, name)
+	fmt.Fprintf(&g.buf, "var %s = []string{", names)
+	for i, f := range fs {
+		if i > 0 {
+			g.buf.WriteString(", ")
+		}
+		g.buf.WriteString(strconv.Quote(f.name))
+	}
+	g.buf.WriteString("}\n")
+
+	fmt.Fprintf(&g.buf, "\n// DecodeFirestore populates x from unwrapped Firestore fields, it implements firestruct.FirestoreDecoder.\n")
+	fmt.Fprintf(&g.buf, "func (x *%s) DecodeFirestore(fields map[string]any) error {\n", name)
+	if len(fs) == 0 {
+		g.buf.WriteString("return nil\n}\n")
+		return nil
+	}
+	g.imports["fmt"] = "fmt"
+	g.imports[firestructPath] = "firestruct"
+	fmt.Fprintf(&g.buf, "var seen [%d]bool\n", len(fs))
+	g.buf.WriteString("var folded []string\n")
+	g.buf.WriteString("for k, v := range fields {\nvar i int\nswitch k {\n")
+	for i, f := range fs {
+		fmt.Fprintf(&g.buf, "case %s:\n", strconv.Quote(f.name))
+		if f.opts.metadata != "" {
+			g.buf.WriteString("// populated with document metadata\ncontinue\n")
+			continue
+		}
+		fmt.Fprintf(&g.buf, "i = %d\n", i)
+	}
+	g.buf.WriteString("default:\n// case insensitive matches are decoded last, an exact match of the same field wins\n")
+	g.buf.WriteString("folded = append(folded, k)\ncontinue\n}\n")
+	g.buf.WriteString("seen[i] = true\nif err := x.decodeFirestoreField(i, v); err != nil {\nreturn err\n}\n}\n")
+	fmt.Fprintf(&g.buf, "for _, k := range folded {\ni := firestruct.FoldField(%s, k)\n", names)
+	g.buf.WriteString("if i < 0 || seen[i] {\ncontinue\n}\nseen[i] = true\n")
+	g.buf.WriteString("if err := x.decodeFirestoreField(i, fields[k]); err != nil {\nreturn err\n}\n}\nreturn nil\n}\n")
+
+	fmt.Fprintf(&g.buf, "\n// decodeFirestoreField populates the field of x with index i in %s.\n", names)
+	fmt.Fprintf(&g.buf, "func (x *%s) decodeFirestoreField(i int, v any) error {\nvar err error\nswitch i {\n", name)
+	for i, f := range fs {
+		if f.opts.metadata != "" {
+			continue
+		}
+		fmt.Fprintf(&g.buf, "case %d:\n", i)
+		expr := g.fieldExpr(t, f.index)
+		fmt.Fprintf(&g.buf, "err = %s\n", g.decodeCall(f.typ, "&"+expr, "v"))
+	}
+	g.buf.WriteString("}\nif err != nil {\n")
+	fmt.Fprintf(&g.buf, "return fmt.Errorf(\"%s.%s.%%s: %%w\", %s[i], err)\n}\nreturn nil\n}\n", g.pkg.Name(), name, names)
+	return nil
+}
+
+// checkEmbedded returns an error for the struct types of the package embedding a type with a generated decoder
+// without having one, since the promoted DecodeFirestore method would only decode the fields of the embedded type.
+func (g *generator) checkEmbedded() error {
+	scope := g.pkg.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || g.decoders[obj] {
+			continue
+		}
+		st, ok := obj.Type().Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		for i := 0; i < st.NumFields(); i++ {
+			f := st.Field(i)
+			if n, ok := unalias(derefType(f.Type())).(*types.Named); ok && f.Embedded() && g.decoders[n.Obj()] {
+				return fmt.Errorf("%s embeds %s, generate a decoder for %s as well", name, n.Obj().Name(), name)
+			}
+		}
+	}
+	return nil
+}
+
+// fieldExpr returns the selector of the field of x with the given index sequence, and writes the allocation
+// of the nil pointers to embedded structs along the way.
+func (g *generator) fieldExpr(t types.Type, index []int) string {
+	expr := "x"
+	for i, x := range index {
+		st := t.Underlying().(*types.Struct)
+		f := st.Field(x)
+		expr += "." + f.Name()
+		t = f.Type()
+		if i == len(index)-1 {
+			break
+		}
+		if p, ok := t.Underlying().(*types.Pointer); ok {
+			fmt.Fprintf(&g.buf, "if %s == nil {\n%s = new(%s)\n}\n", expr, expr, g.typeString(p.Elem()))
+			t = p.Elem()
+		}
+	}
+	return expr
+}
+
+// decodeCall returns the call decoding the value v into the value of type t that p points to.
+func (g *generator) decodeCall(t types.Type, p, v string) string {
+	if fn, _ := g.decodeFunc(t); fn != "" {
+		return fmt.Sprintf("%s(%s, %s)", fn, p, v)
+	}
+
+	// Type inference of the generic functions requires unnamed slices, maps and pointers
+	switch x := unalias(t).(type) {
+	case *types.Slice:
+		return fmt.Sprintf("firestruct.DecodeSlice(%s, %s, %s)", p, v, g.elemFunc(x.Elem()))
+	case *types.Map:
+		if k, ok := x.Key().Underlying().(*types.Basic); ok && k.Kind() == types.String {
+			return fmt.Sprintf("firestruct.DecodeMap(%s, %s, %s)", p, v, g.elemFunc(x.Elem()))
+		}
+	case *types.Pointer:
+		return fmt.Sprintf("firestruct.DecodePtr(%s, %s, %s)", p, v, g.elemFunc(x.Elem()))
+	}
+	return fmt.Sprintf("firestruct.DecodeValue(%s, %s)", p, v)
+}
+
+// decodeFunc returns the firestruct function decoding a value of type t, if t does not need a function literal,
+// and whether the function is generic.
+func (g *generator) decodeFunc(t types.Type) (fn string, generic bool) {
+	t = unalias(t)
+	switch {
+	case types.Identical(t, types.NewSlice(types.Typ[types.Byte])):
+		return "firestruct.DecodeBytes", false
+	case isNamed(t, "time", "Time"):
+		return "firestruct.DecodeTime", false
+	case isNamed(t, "github.com/google/uuid", "UUID"):
+		return "firestruct.DecodeUUID", false
+	case g.isDecoder(t):
+		return "firestruct.DecodeStruct", true
+	}
+
+	switch x := t.Underlying().(type) {
+	case *types.Basic:
+		switch x.Kind() {
+		case types.Bool:
+			return "firestruct.DecodeBool", true
+		case types.String:
+			return "firestruct.DecodeString", true
+		case types.Int, types.Int8, types.Int16, types.Int32, types.Int64:
+			return "firestruct.DecodeInt", true
+		case types.Uint8, types.Uint16, types.Uint32:
+			return "firestruct.DecodeUint", true
+		case types.Float32, types.Float64:
+			return "firestruct.DecodeFloat", true
+		}
+	case *types.Interface:
+		if _, ok := t.(*types.Interface); ok && x.Empty() {
+			return "firestruct.DecodeAny", false
+		}
+	}
+	return "", false
+}
+
+// elemFunc returns the function decoding the elements of type t of a slice, map or pointer.
+func (g *generator) elemFunc(t types.Type) string {
+	switch fn, generic := g.decodeFunc(t); {
+	case generic:
+		return fmt.Sprintf("%s[%s]", fn, g.typeString(t))
+	case fn != "":
+		return fn
+	}
+	return fmt.Sprintf("func(p *%s, v any) error {\nreturn %s\n}", g.typeString(t), g.decodeCall(t, "p", "v"))
+}
+
+// isDecoder reports whether t is a struct type with a generated decoder.
+func (g *generator) isDecoder(t types.Type) bool {
+	n, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	if _, ok := n.Underlying().(*types.Struct); !ok {
+		return false
+	}
+	if g.decoders[n.Obj()] {
+		return true
+	}
+	// A method promoted from an embedded struct only decodes the fields of the embedded struct
+	sig, ok := lookupMethod(n, "DecodeFirestore")
+	if !ok || derefType(sig.Recv().Type()) != n {
+		return false
+	}
+	return sig.Params().Len() == 1 && sig.Results().Len() == 1 &&
+		types.Identical(sig.Params().At(0).Type(), types.NewMap(types.Typ[types.String], types.Universe.Lookup("any").Type())) &&
+		types.Identical(sig.Results().At(0).Type(), types.Universe.Lookup("error").Type())
+}
+
+// lookupMethod returns the signature of the method of *t with the given name.
+func lookupMethod(t *types.Named, name string) (*types.Signature, bool) {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), false, t.Obj().Pkg(), name)
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return nil, false
+	}
+	return fn.Type().(*types.Signature), true
+}
+
+// typeString returns the Go syntax of t in the generated file, and records the packages it references.
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		g.imports[p.Path()] = p.Name()
+		return p.Name()
+	})
+}
+
+// isStdPath reports whether path is the import path of a standard library package.
+func isStdPath(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// isNamed reports whether t is the defined type name of the package with the given path.
+func isNamed(t types.Type, path, name string) bool {
+	n, ok := t.(*types.Named)
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == path && n.Obj().Name() == name
+}
+
+// isLeafType reports whether t is decoded as a single value even though it is a struct, like the firestruct field cache.
+func isLeafType(t types.Type) bool {
+	t = unalias(t)
+	if p, ok := t.(*types.Pointer); ok {
+		return isNamed(unalias(p.Elem()), "google.golang.org/protobuf/types/known/timestamppb", "Timestamp")
+	}
+	return isNamed(t, "time", "Time") || isNamed(t, "google.golang.org/genproto/googleapis/type/latlng", "LatLng")
+}
+
+// tagOptions are the options of a firestore struct tag that affect decoding.
+type tagOptions struct {
+	inline     bool
+	remain     bool
+	metadata   string
+	hasDefault bool
+}
+
+// parseTag interprets a firestore struct tag like the firestruct package does.
+func parseTag(tag string) (name string, keep bool, opts tagOptions, err error) {
+	name, keep, options, err := fields.ParseStandardTag("firestore", reflect.StructTag(tag))
+	if err != nil {
+		return "", false, opts, err
+	}
+	for _, opt := range options {
+		switch {
+		case opt == "omitempty":
+		case opt == "inline":
+			opts.inline = true
+		case opt == "remain":
+			opts.remain = true
+		case opt == "docid", opt == "docname", opt == "createTime", opt == "updateTime":
+			opts.metadata = opt
+		case strings.HasPrefix(opt, "default="):
+			opts.hasDefault = true
+		default:
+			return "", false, opts, fmt.Errorf("unknown tag option: %q", opt)
+		}
+	}
+	return name, keep, opts, nil
+}
+
+// A field is a struct field populated from a document field.
+type field struct {
+	name        string // Firestore field name
+	nameFromTag bool
+	typ         types.Type
+	opts        tagOptions
+	index       []int // index sequence of the field in nested embedded structs
+}
+
+// structFields returns the fields of struct type t populated from document fields, sorted by index,
+// following the same Go embedding rules as the field cache of the firestruct package.
+func structFields(t types.Type) ([]field, error) {
+	fs, err := listFields(t)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(fs, func(i, j int) bool {
+		x, y := fs[i], fs[j]
+		if x.name != y.name {
+			return x.name < y.name
+		}
+		if len(x.index) != len(y.index) {
+			return len(x.index) < len(y.index)
+		}
+		if x.nameFromTag != y.nameFromTag {
+			return x.nameFromTag
+		}
+		return lessIndex(x.index, y.index)
+	})
+
+	// The first field with a given name is the dominant one, unless another field has the same depth and tag presence.
+	var out []field
+	for advance, i := 0, 0; i < len(fs); i += advance {
+		for advance = 1; i+advance < len(fs) && fs[i+advance].name == fs[i].name; advance++ {
+		}
+		if advance > 1 && len(fs[i].index) == len(fs[i+1].index) && fs[i].nameFromTag == fs[i+1].nameFromTag {
+			continue
+		}
+		out = append(out, fs[i])
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return lessIndex(out[i].index, out[j].index)
+	})
+	return out, nil
+}
+
+// listFields lists the fields of struct type t and of its embedded structs, breadth first.
+func listFields(t types.Type) ([]field, error) {
+	type fieldScan struct {
+		typ   types.Type
+		index []int
+	}
+	key := func(t types.Type) string {
+		return types.TypeString(t, nil)
+	}
+
+	current := []fieldScan{}
+	next := []fieldScan{{typ: t}}
+	var nextCount map[string]int
+	visited := map[string]bool{}
+
+	var fs []field
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count := nextCount
+		nextCount = nil
+
+		for _, scan := range current {
+			t := scan.typ
+			if visited[key(t)] {
+				continue
+			}
+			visited[key(t)] = true
+			st, ok := t.Underlying().(*types.Struct)
+			if !ok {
+				continue
+			}
+			for i := 0; i < st.NumFields(); i++ {
+				f := st.Field(i)
+				exported := f.Exported()
+				if !exported && !f.Embedded() {
+					continue
+				}
+
+				tagName, keep, opts, err := parseTag(st.Tag(i))
+				if err != nil {
+					return nil, err
+				}
+				if !keep {
+					continue
+				}
+				index := append(append([]int{}, scan.index...), i)
+				newField := func() field {
+					name := tagName
+					if name == "" {
+						name = f.Name()
+					}
+					return field{name: name, nameFromTag: tagName != "", typ: f.Type(), opts: opts, index: index}
+				}
+				if isLeafType(f.Type()) {
+					fs = append(fs, newField())
+					continue
+				}
+
+				var ntyp types.Type
+				inline := false
+				if opts.inline {
+					ntyp = derefType(f.Type())
+					if _, ok := ntyp.Underlying().(*types.Struct); !ok {
+						return nil, fmt.Errorf("inlined field %s of %s is not a struct", f.Name(), key(t))
+					}
+					if !exported {
+						continue
+					}
+					inline = true
+				} else if f.Embedded() {
+					ntyp = derefType(f.Type())
+				}
+
+				if !inline && (tagName != "" || ntyp == nil || !isStruct(ntyp)) {
+					if !exported {
+						continue
+					}
+					fs = append(fs, newField())
+					if count[key(t)] > 1 {
+						// A duplicate makes the field annihilate itself
+						fs = append(fs, fs[len(fs)-1])
+					}
+					continue
+				}
+
+				if nextCount[key(ntyp)] > 0 {
+					nextCount[key(ntyp)] = 2
+					continue
+				}
+				if nextCount == nil {
+					nextCount = map[string]int{}
+				}
+				nextCount[key(ntyp)] = 1
+				if count[key(t)] > 1 {
+					nextCount[key(ntyp)] = 2
+				}
+				next = append(next, fieldScan{ntyp, index})
+			}
+		}
+	}
+	return fs, nil
+}
+
+func derefType(t types.Type) types.Type {
+	if p, ok := unalias(t).(*types.Pointer); ok {
+		return p.Elem()
+	}
+	return t
+}
+
+func isStruct(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Struct)
+	return ok
+}
+
+func lessIndex(x, y []int) bool {
+	for k := 0; k < len(x) && k < len(y); k++ {
+		if x[k] != y[k] {
+			return x[k] < y[k]
+		}
+	}
+	return len(x) < len(y)
+}
+
+// unalias returns the type denoted by the alias t, or t if it is not an alias.
+// Aliases such as any are only represented by types of their own in recent versions of go/types.
+func unalias(t types.Type) types.Type {
+	for {
+		a, ok := t.(interface{ Rhs() types.Type })
+		if !ok {
+			return t
+		}
+		t = a.Rhs()
+	}
+}
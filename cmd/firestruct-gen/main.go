@@ -0,0 +1,96 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command firestruct-gen generates reflection-free Firestore decoders for Go struct types.
+//
+// Usage:
+//
+//	firestruct-gen -type T[,T...] [-output file] [dir]
+//
+// It is meant to be run by go generate, from a directive in the package declaring the types:
+//
+//	//go:generate firestruct-gen -type Task,Address
+//
+// For every type, a DecodeFirestore method implementing firestruct.FirestoreDecoder is written to the output file,
+// <type>_firestruct.go in the package directory by default, named after the first type. DataTo calls it instead of
+// populating the struct with reflection. Fields are matched with the same rules as DataTo: firestore tag names,
+// case insensitive matching where an exact match wins, and embedded and inlined structs. Fields of types without a
+// specialized decoder, such as arrays or structs without a generated decoder, are still decoded with reflection.
+//
+// Types with remain or default tag options, or implementing firestruct.Defaulter, are not supported.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+// run executes the command line args and returns the process exit code.
+func run(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("firestruct-gen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	typeNames := fs.String("type", "", "comma-separated list of struct type names, required")
+	output := fs.String("output", "", "output file name, <type>_firestruct.go in the package directory by default")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: firestruct-gen -type T[,T...] [-output file] [dir]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *typeNames == "" || fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	names := strings.Split(*typeNames, ",")
+	if err := generateFile(dir, names, *output); err != nil {
+		fmt.Fprintf(stderr, "firestruct-gen: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// generateFile writes the decoders of the named types of the package in dir to output.
+func generateFile(dir string, names []string, output string) error {
+	pkg, err := loadPackage(dir)
+	if err != nil {
+		return err
+	}
+	src, err := generate(pkg, names)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = filepath.Join(dir, strings.ToLower(names[0])+"_firestruct.go")
+	}
+	return os.WriteFile(output, src, 0o644)
+}
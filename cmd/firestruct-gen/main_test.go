@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGenerateGentest checks that the decoders checked in to internal/gentest are up to date.
+func TestGenerateGentest(t *testing.T) {
+	dir := filepath.Join("..", "..", "internal", "gentest")
+	pkg, err := loadPackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := generate(pkg, []string{"Task", "Address", "Owner"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := os.ReadFile(filepath.Join(dir, "task_firestruct.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, expected) {
+		t.Errorf("generate() output does not match internal/gentest/task_firestruct.go, run go generate ./internal/gentest")
+	}
+}
+
+type generateTableTest struct {
+	Name     string
+	Source   string
+	Args     []string
+	Expected string // substring of the generated file, or of stderr when the exit code is not 0
+	Code     int
+}
+
+var generateTests = []generateTableTest{
+	{
+		Name:     "struct",
+		Source:   "type Doc struct {\n\tName string `firestore:\"name\"`\n}\n",
+		Args:     []string{"-type", "Doc"},
+		Expected: "func (x *Doc) DecodeFirestore(fields map[string]any) error {",
+	},
+	{
+		Name:     "struct without fields",
+		Source:   "type Doc struct {\n\tname string\n}\n",
+		Args:     []string{"-type", "Doc"},
+		Expected: "func (x *Doc) DecodeFirestore(fields map[string]any) error {\n\treturn nil\n}",
+	},
+	{
+		Name:     "unknown type",
+		Source:   "type Doc struct{}\n",
+		Args:     []string{"-type", "Missing"},
+		Expected: "type Missing not found",
+		Code:     1,
+	},
+	{
+		Name:     "not a struct",
+		Source:   "type Doc int\n",
+		Args:     []string{"-type", "Doc"},
+		Expected: "type Doc is not a struct",
+		Code:     1,
+	},
+	{
+		Name:     "remain option",
+		Source:   "type Doc struct {\n\tRest map[string]any `firestore:\",remain\"`\n}\n",
+		Args:     []string{"-type", "Doc"},
+		Expected: "Doc.Rest: the remain option is not supported",
+		Code:     1,
+	},
+	{
+		Name:     "default option",
+		Source:   "type Doc struct {\n\tName string `firestore:\"name,default=x\"`\n}\n",
+		Args:     []string{"-type", "Doc"},
+		Expected: "Doc.name: the default option is not supported",
+		Code:     1,
+	},
+	{
+		Name:     "defaulter",
+		Source:   "type Doc struct{}\n\nfunc (d *Doc) Defaults() map[string]any { return nil }\n",
+		Args:     []string{"-type", "Doc"},
+		Expected: "Doc implements Defaulter",
+		Code:     1,
+	},
+	{
+		Name:     "unknown tag option",
+		Source:   "type Doc struct {\n\tName string `firestore:\"name,foo\"`\n}\n",
+		Args:     []string{"-type", "Doc"},
+		Expected: `unknown tag option: "foo"`,
+		Code:     1,
+	},
+	{
+		Name:     "embedding type without decoder",
+		Source:   "type Base struct{ Name string }\n\ntype Doc struct {\n\tBase\n}\n",
+		Args:     []string{"-type", "Base"},
+		Expected: "Doc embeds Base, generate a decoder for Doc as well",
+		Code:     1,
+	},
+	{Name: "no type", Source: "type Doc struct{}\n", Args: []string{}, Code: 2},
+}
+
+func TestRun(t *testing.T) {
+	thisFunctionName := "run"
+	for _, test := range generateTests {
+		t.Run(test.Name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "doc.go"), []byte("package doc\n\n"+test.Source), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			var stderr bytes.Buffer
+			code := run(append(test.Args, dir), &stderr)
+			if code != test.Code {
+				t.Fatalf("%v() test \"%v\" returned exit code %d, stderr: %s", thisFunctionName, test.Name, code, stderr.String())
+			}
+			if test.Code != 0 {
+				if !strings.Contains(stderr.String(), test.Expected) {
+					t.Errorf("%v() test \"%v\" output does not match expected data:\n%s\n%s", thisFunctionName, test.Name, stderr.String(), test.Expected)
+				}
+				return
+			}
+
+			src, err := os.ReadFile(filepath.Join(dir, "doc_firestruct.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(src), test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%s\n%s", thisFunctionName, test.Name, src, test.Expected)
+			}
+		})
+	}
+}
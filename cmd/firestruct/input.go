@@ -0,0 +1,172 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bennovw/firestruct"
+)
+
+// An input is a single Firestore event or document read from the input.
+type input struct {
+	event *firestruct.FirestoreCloudEvent
+	doc   *firestruct.FirestoreDocument
+}
+
+// document returns the document of the input, for events the value or oldValue is returned.
+func (in input) document(old bool) *firestruct.FirestoreDocument {
+	if in.event == nil {
+		return in.doc
+	}
+	if old {
+		return &in.event.OldValue
+	}
+	return &in.event.Value
+}
+
+// forEachInput calls fn for every event or document found in the files, or in stdin when files is empty or "-".
+func forEachInput(files []string, stdin io.Reader, fn func(input) error) error {
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	for _, name := range files {
+		if name == "-" {
+			if err := decodeInputs(stdin, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := decodeFile(name, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeFile calls fn for every event or document found in the named file.
+func decodeFile(name string, fn func(input) error) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := decodeInputs(f, fn); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// decodeInputs reads a stream of JSON values from r and calls fn for every event or document they contain.
+func decodeInputs(r io.Reader, fn func(input) error) error {
+	dec := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		inputs, err := parseInputs(raw)
+		if err != nil {
+			return err
+		}
+		for _, in := range inputs {
+			if err := fn(in); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parseInputs detects the kind of a JSON value and returns the events or documents it contains.
+func parseInputs(raw json.RawMessage) ([]input, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '[' {
+		// A REST runQuery response is an array of results
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		var inputs []input
+		for _, item := range items {
+			in, err := parseInputs(item)
+			if err != nil {
+				return nil, err
+			}
+			inputs = append(inputs, in...)
+		}
+		return inputs, nil
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return nil, fmt.Errorf("expecting a JSON object or array: %v", err)
+	}
+
+	switch {
+	case has(keys, "specversion"):
+		// A Cloud Event in structured JSON mode
+		data, ok := keys["data"]
+		if !ok {
+			return nil, errors.New("cloud event without JSON data, protobuf encoded events are not supported")
+		}
+		return parseInputs(data)
+
+	case has(keys, "value", "oldValue", "updateMask"):
+		var event firestruct.FirestoreCloudEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return nil, fmt.Errorf("invalid Firestore event: %v", err)
+		}
+		return []input{{event: &event}}, nil
+
+	case has(keys, "document"):
+		// A runQuery result
+		return parseInputs(keys["document"])
+
+	case has(keys, "fields", "name"):
+		var doc firestruct.FirestoreDocument
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return nil, fmt.Errorf("invalid Firestore document: %v", err)
+		}
+		return []input{{doc: &doc}}, nil
+
+	case has(keys, "readTime"):
+		// A runQuery result without a document, e.g. when the query has no results
+		return nil, nil
+	}
+
+	return nil, errors.New("unrecognized input, expecting a Firestore event, document or runQuery response")
+}
+
+// has reports whether any of the names is a key of m.
+func has(m map[string]json.RawMessage, names ...string) bool {
+	for _, name := range names {
+		if _, ok := m[name]; ok {
+			return true
+		}
+	}
+	return false
+}
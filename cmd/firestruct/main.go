@@ -0,0 +1,320 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command firestruct unwraps Firestore protojson encoded documents and events for inspection.
+//
+// Usage:
+//
+//	firestruct <command> [flags] [file ...]
+//
+// Input is read from the files, or from stdin when no file is given. Each input may contain a Cloud Event
+// in structured JSON mode, the data of a Firestore Cloud Event, a single Document, a REST runQuery response,
+// or any number of newline-delimited values of these types.
+//
+// The commands are:
+//
+//	unwrap  print documents as plain JSON
+//	flat    print documents as sorted key=value lines
+//	diff    print the fields that changed between the oldValue and value of Firestore events
+//	struct  print a Go struct definition inferred from all input documents
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bennovw/firestruct"
+)
+
+const usage = `usage: firestruct <command> [flags] [file ...]
+
+Reads Firestore protojson from the files, or from stdin when no file is given.
+The input may be a Cloud Event, the data of a Firestore Cloud Event, a Document,
+a REST runQuery response, or newline-delimited values of these types.
+
+Commands:
+  unwrap  print documents as plain JSON
+  flat    print documents as sorted key=value lines
+  diff    print the fields that changed between the oldValue and value of Firestore events
+  struct  print a Go struct definition inferred from all input documents
+
+Run "firestruct <command> -h" for the flags of a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the process exit code.
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var cmd func([]string, io.Reader, io.Writer) error
+	switch args[0] {
+	case "unwrap":
+		cmd = runUnwrap
+	case "flat":
+		cmd = runFlat
+	case "diff":
+		cmd = runDiff
+	case "struct":
+		cmd = runStruct
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "firestruct: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	if err := cmd(args[1:], stdin, stdout); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(stderr, "firestruct %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// newFlagSet returns a flag set for a command that reports parse errors instead of exiting.
+func newFlagSet(name string, out io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprintf(out, "usage: firestruct %s [flags] [file ...]\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func runUnwrap(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := newFlagSet("unwrap", stdout)
+	compact := fs.Bool("compact", false, "print each document on a single line")
+	metadata := fs.Bool("metadata", false, "include the document name, createTime and updateTime")
+	plain := fs.Bool("plain", false, "print bytes and references as plain strings instead of $bytes and $reference objects")
+	old := fs.Bool("old", false, "print the oldValue of Firestore events instead of their value")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var opts []firestruct.JSONOption
+	if !*compact {
+		opts = append(opts, firestruct.WithJSONIndent("  "))
+	}
+	if *metadata {
+		opts = append(opts, firestruct.WithDocumentMetadata())
+	}
+	if *plain {
+		opts = append(opts, firestruct.WithoutTypeMarkers())
+	}
+
+	return forEachInput(fs.Args(), stdin, func(in input) error {
+		doc := in.document(*old)
+		if doc == nil {
+			return nil
+		}
+		b, err := doc.MarshalPlainJSON(opts...)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(stdout, "%s\n", b)
+		return err
+	})
+}
+
+func runFlat(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := newFlagSet("flat", stdout)
+	dots := fs.Bool("dots", false, "write array indexes as tags.0 instead of tags[0]")
+	old := fs.Bool("old", false, "print the oldValue of Firestore events instead of their value")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	first := true
+	return forEachInput(fs.Args(), stdin, func(in input) error {
+		doc := in.document(*old)
+		if doc == nil {
+			return nil
+		}
+		flat, err := plainFlatMap(doc, *dots)
+		if err != nil {
+			return err
+		}
+
+		if !first {
+			fmt.Fprintln(stdout)
+		}
+		first = false
+		if doc.Name != "" {
+			fmt.Fprintf(stdout, "# %s\n", doc.Name)
+		}
+		for _, k := range sortedKeys(flat) {
+			fmt.Fprintf(stdout, "%s=%s\n", k, flat[k])
+		}
+		return nil
+	})
+}
+
+func runDiff(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := newFlagSet("diff", stdout)
+	dots := fs.Bool("dots", false, "write array indexes as tags.0 instead of tags[0]")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	first := true
+	return forEachInput(fs.Args(), stdin, func(in input) error {
+		if in.event == nil {
+			return errors.New("diff requires Firestore events with an oldValue and value")
+		}
+		oldFlat, err := plainFlatMap(&in.event.OldValue, *dots)
+		if err != nil {
+			return fmt.Errorf("oldValue: %v", err)
+		}
+		newFlat, err := plainFlatMap(&in.event.Value, *dots)
+		if err != nil {
+			return fmt.Errorf("value: %v", err)
+		}
+
+		if !first {
+			fmt.Fprintln(stdout)
+		}
+		first = false
+		name := in.event.Value.Name
+		if name == "" {
+			name = in.event.OldValue.Name
+		}
+		if name != "" {
+			fmt.Fprintf(stdout, "# %s\n", name)
+		}
+
+		keys := sortedKeys(oldFlat)
+		for k := range newFlat {
+			if _, ok := oldFlat[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			o, inOld := oldFlat[k]
+			n, inNew := newFlat[k]
+			if inOld && inNew && o == n {
+				continue
+			}
+			if inOld {
+				fmt.Fprintf(stdout, "- %s=%s\n", k, o)
+			}
+			if inNew {
+				fmt.Fprintf(stdout, "+ %s=%s\n", k, n)
+			}
+		}
+		return nil
+	})
+}
+
+func runStruct(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := newFlagSet("struct", stdout)
+	name := fs.String("name", "Document", "name of the generated struct")
+	pkg := fs.String("package", "main", "package name of the generated file, only type declarations are printed when empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Both versions of the document in events are used as samples
+	var samples []map[string]any
+	err := forEachInput(fs.Args(), stdin, func(in input) error {
+		for _, doc := range []*firestruct.FirestoreDocument{in.document(true), in.document(false)} {
+			if doc != nil && doc.Fields != nil {
+				samples = append(samples, doc.Fields)
+			}
+			if in.event == nil {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(samples) == 0 {
+		return errors.New("no documents found in the input")
+	}
+
+	var opts []firestruct.GenerateOption
+	if *pkg != "" {
+		opts = append(opts, firestruct.WithPackageName(*pkg))
+	}
+	src, err := firestruct.GenerateStruct(*name, samples, opts...)
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(src)
+	return err
+}
+
+// plainFlatMap flattens the plain JSON representation of doc and returns each value encoded as JSON.
+// Going through plain JSON gives every Firestore data type a readable and comparable representation.
+func plainFlatMap(doc *firestruct.FirestoreDocument, dots bool) (map[string]string, error) {
+	if doc.Fields == nil {
+		return map[string]string{}, nil
+	}
+
+	b, err := doc.MarshalPlainJSON(firestruct.WithoutTypeMarkers())
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+
+	style := firestruct.ArrayIndexBrackets
+	if dots {
+		style = firestruct.ArrayIndexDots
+	}
+	flat := make(map[string]string)
+	for k, v := range firestruct.Flatten(m, firestruct.WithArrayIndexStyle(style)) {
+		var sb strings.Builder
+		enc := json.NewEncoder(&sb)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+		flat[k] = strings.TrimSuffix(sb.String(), "\n")
+	}
+	return flat, nil
+}
+
+// sortedKeys returns the keys of m in lexicographical order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDocumentJSON = `{
+	"name": "projects/p/databases/(default)/documents/users/jane",
+	"fields": {
+		"name": {"stringValue": "Jane"},
+		"age": {"integerValue": "42"},
+		"tags": {"arrayValue": {"values": [{"stringValue": "a"}, {"stringValue": "b"}]}},
+		"address": {"mapValue": {"fields": {"city": {"stringValue": "Ghent"}}}}
+	},
+	"createTime": "2025-04-14T01:02:03Z",
+	"updateTime": "2025-04-14T01:02:03Z"
+}`
+
+const testEventJSON = `{
+	"oldValue": {
+		"name": "projects/p/databases/(default)/documents/users/jane",
+		"fields": {
+			"name": {"stringValue": "Jane"},
+			"age": {"integerValue": "41"},
+			"email": {"stringValue": "jane@example.com"}
+		}
+	},
+	"value": ` + testDocumentJSON + `,
+	"updateMask": {"fieldPaths": ["age", "email", "tags", "address"]}
+}`
+
+type cliTableTest struct {
+	Name     string
+	Args     []string
+	Input    string
+	Expected string
+	Code     int
+}
+
+var cliTests = []cliTableTest{
+	{
+		Name:     "unwrap document",
+		Args:     []string{"unwrap", "-compact"},
+		Input:    testDocumentJSON,
+		Expected: `{"address":{"city":"Ghent"},"age":42,"name":"Jane","tags":["a","b"]}` + "\n",
+	},
+	{
+		Name:     "unwrap old value of event with metadata",
+		Args:     []string{"unwrap", "-compact", "-old", "-metadata"},
+		Input:    testEventJSON,
+		Expected: `{"fields":{"age":41,"email":"jane@example.com","name":"Jane"},"name":"projects/p/databases/(default)/documents/users/jane"}` + "\n",
+	},
+	{
+		Name:     "unwrap newline-delimited documents",
+		Args:     []string{"unwrap", "-compact"},
+		Input:    `{"fields": {"a": {"booleanValue": true}}}` + "\n" + `{"fields": {"b": {"nullValue": null}}}`,
+		Expected: `{"a":true}` + "\n" + `{"b":null}` + "\n",
+	},
+	{
+		Name: "unwrap runQuery response",
+		Args: []string{"unwrap", "-compact"},
+		Input: `[{"readTime": "2025-04-14T01:02:03Z"},
+			{"document": {"name": "a", "fields": {"a": {"doubleValue": 1.5}}}, "readTime": "2025-04-14T01:02:03Z"}]`,
+		Expected: `{"a":1.5}` + "\n",
+	},
+	{
+		Name:     "unwrap structured cloud event",
+		Args:     []string{"unwrap", "-compact"},
+		Input:    `{"specversion": "1.0", "type": "google.cloud.firestore.document.v1.written", "data": ` + testEventJSON + `}`,
+		Expected: `{"address":{"city":"Ghent"},"age":42,"name":"Jane","tags":["a","b"]}` + "\n",
+	},
+	{
+		Name:  "flat event",
+		Args:  []string{"flat"},
+		Input: testEventJSON,
+		Expected: `# projects/p/databases/(default)/documents/users/jane
+address.city="Ghent"
+age=42
+name="Jane"
+tags[0]="a"
+tags[1]="b"
+`,
+	},
+	{
+		Name:  "diff event",
+		Args:  []string{"diff", "-dots"},
+		Input: testEventJSON,
+		Expected: `# projects/p/databases/(default)/documents/users/jane
++ address.city="Ghent"
+- age=41
++ age=42
+- email="jane@example.com"
++ tags.0="a"
++ tags.1="b"
+`,
+	},
+	{
+		Name:  "struct from event",
+		Args:  []string{"struct", "-name", "User", "-package", ""},
+		Input: testEventJSON,
+		Expected: "// User was generated from 2 sample Firestore documents.\n" +
+			"type User struct {\n" +
+			"\tAddress UserAddress `firestore:\"address,omitempty\"`\n" +
+			"\tAge     int64       `firestore:\"age\"`\n" +
+			"\tEmail   string      `firestore:\"email,omitempty\"`\n" +
+			"\tName    string      `firestore:\"name\"`\n" +
+			"\tTags    []string    `firestore:\"tags,omitempty\"`\n" +
+			"}\n\n" +
+			"// UserAddress is the type of User.Address.\n" +
+			"type UserAddress struct {\n" +
+			"\tCity string `firestore:\"city\"`\n" +
+			"}\n",
+	},
+	{Name: "struct without documents", Args: []string{"struct"}, Input: `[{"readTime": "2025-04-14T01:02:03Z"}]`, Code: 1},
+	{Name: "diff document", Args: []string{"diff"}, Input: testDocumentJSON, Code: 1},
+	{Name: "invalid input", Args: []string{"unwrap"}, Input: `{"foo": 1}`, Code: 1},
+	{Name: "invalid json", Args: []string{"unwrap"}, Input: `{`, Code: 1},
+	{Name: "unknown command", Args: []string{"foo"}, Code: 2},
+	{Name: "no command", Args: []string{}, Code: 2},
+	{Name: "unknown flag", Args: []string{"flat", "-foo"}, Code: 1},
+}
+
+func TestRun(t *testing.T) {
+	thisFunctionName := "run"
+	for _, test := range cliTests {
+		t.Run(test.Name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(test.Args, strings.NewReader(test.Input), &stdout, &stderr)
+			if code != test.Code {
+				t.Errorf("%v() test \"%v\" returned exit code %d, stderr: %s", thisFunctionName, test.Name, code, stderr.String())
+			}
+			if test.Code == 0 && stdout.String() != test.Expected {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%s\n%s", thisFunctionName, test.Name, stdout.String(), test.Expected)
+			}
+		})
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "doc.json")
+	if err := os.WriteFile(path, []byte(testDocumentJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"unwrap", "-compact", path, "-"}, strings.NewReader(`{"fields": {}}`), &stdout, &stderr)
+	if code != 0 {
+		t.Errorf("run() returned exit code %d, stderr: %s", code, stderr.String())
+	}
+	expected := `{"address":{"city":"Ghent"},"age":42,"name":"Jane","tags":["a","b"]}` + "\n{}\n"
+	if stdout.String() != expected {
+		t.Errorf("run() output does not match expected data:\n%s", stdout.String())
+	}
+
+	code = run([]string{"unwrap", filepath.Join(dir, "missing.json")}, strings.NewReader(""), &stdout, &stderr)
+	if code != 1 {
+		t.Errorf("run() with a missing file returned exit code %d", code)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Limits of the Firestore data model, see https://firebase.google.com/docs/firestore/quotas
+const (
+	// MaxFieldDepth is the maximum depth of fields in maps and arrays. Top level fields have depth 1, and every map or array adds a level.
+	MaxFieldDepth = 20
+	// MaxFieldNameSize is the maximum size of a field name in bytes.
+	MaxFieldNameSize = 1500
+	// MaxFieldValueSize is the maximum size of a field value in bytes, 1 MiB - 89 bytes.
+	MaxFieldValueSize = MaxDocumentSize - 89
+)
+
+// Rules reported by CheckConstraints in ValidationError.Rule.
+const (
+	ConstraintNestedArray  = "nestedArray"  // an array directly contains an array
+	ConstraintDepth        = "depth"        // fields are nested deeper than MaxFieldDepth
+	ConstraintReservedName = "reservedName" // a field name matches __.*__
+	ConstraintEmptyName    = "emptyName"    // a field name is empty
+	ConstraintNameSize     = "nameSize"     // a field name is larger than MaxFieldNameSize
+	ConstraintValueSize    = "valueSize"    // a string or bytes value is larger than MaxFieldValueSize
+	ConstraintDocumentSize = "documentSize" // the document is larger than MaxDocumentSize
+)
+
+// CheckConstraints checks that v can be written to Firestore, v must be a struct, a map with string keys such as
+// an unwrapped document, or a pointer to either. Structs are converted using the same struct tags as DataTo, see ValueOf.
+//
+// Every violation of the Firestore data model is reported as a ValidationError in ValidationErrors: arrays directly
+// containing arrays, fields nested deeper than MaxFieldDepth, field names that are empty, larger than MaxFieldNameSize
+// or reserved, i.e. matching __.*__, strings and bytes larger than MaxFieldValueSize, and documents larger than MaxDocumentSize.
+// Vectors unwrapped to a map with a "__type__" field, e.g. by ToMap, are not reported as reserved names.
+// The size of the document name is not included, use FirestoreDocument.CheckConstraints to include it.
+func CheckConstraints(v any) error {
+	x, err := ValueOf(v)
+	if err != nil {
+		return err
+	}
+	m, ok := x.(MapValue)
+	if !ok {
+		return fmt.Errorf("cannot check the constraints of %T, expecting a struct or map", v)
+	}
+	return checkConstraints("", m)
+}
+
+// CheckConstraints checks that the document can be written to Firestore, see the package level CheckConstraints.
+func (d *FirestoreDocument) CheckConstraints() error {
+	values, err := d.Values()
+	if err != nil {
+		return err
+	}
+	return checkConstraints(d.Name, values)
+}
+
+// checkConstraints checks the fields of the document with the given name.
+func checkConstraints(name string, fields MapValue) error {
+	c := &constraintChecker{}
+	c.fields(nil, fields, 1)
+
+	if size := newDocumentSize(name, fields); size.Total > MaxDocumentSize {
+		c.fail(nil, ConstraintDocumentSize, "document size of %d bytes exceeds the maximum of %d bytes", size.Total, MaxDocumentSize)
+	}
+
+	if len(c.errs) > 0 {
+		return c.errs
+	}
+	return nil
+}
+
+// constraintChecker collects the violations of the Firestore data model.
+type constraintChecker struct {
+	errs ValidationErrors
+}
+
+func (c *constraintChecker) fail(path FieldPath, rule string, format string, args ...any) {
+	c.errs = append(c.errs, &ValidationError{Path: path, Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+// fields checks the fields of a map nested at path, the fields have the given depth.
+func (c *constraintChecker) fields(path FieldPath, m MapValue, depth int) {
+	for _, k := range sortedKeys(m) {
+		fp := appendFieldPath(path, k)
+		switch {
+		case k == "":
+			c.fail(fp, ConstraintEmptyName, "name must not be empty")
+		case len(k) > MaxFieldNameSize:
+			c.fail(fp, ConstraintNameSize, "name size of %d bytes exceeds the maximum of %d bytes", len(k), MaxFieldNameSize)
+		case len(k) >= 4 && strings.HasPrefix(k, "__") && strings.HasSuffix(k, "__"):
+			c.fail(fp, ConstraintReservedName, "name is reserved, names must not match __.*__")
+		}
+		c.value(fp, m[k], depth)
+	}
+}
+
+// value checks a value at path with the given depth, and the values nested in it.
+func (c *constraintChecker) value(path FieldPath, v Value, depth int) {
+	switch x := derefValue(v).(type) {
+	case StringValue, BytesValue:
+		if size := ValueSize(x); size > MaxFieldValueSize {
+			c.fail(path, ConstraintValueSize, "size of %d bytes exceeds the maximum of %d bytes", size, MaxFieldValueSize)
+		}
+
+	case MapValue:
+		if len(x) == 0 {
+			return
+		}
+		if _, ok := vectorOf(x); ok {
+			// an unwrapped vector, whose reserved __type__ field is written by Firestore itself
+			return
+		}
+		if depth >= MaxFieldDepth {
+			c.fail(path, ConstraintDepth, "exceeds the maximum depth of %d", MaxFieldDepth)
+			return
+		}
+		c.fields(path, x, depth+1)
+
+	case ArrayValue:
+		if len(x) == 0 {
+			return
+		}
+		if depth >= MaxFieldDepth {
+			c.fail(path, ConstraintDepth, "exceeds the maximum depth of %d", MaxFieldDepth)
+			return
+		}
+		nested := false
+		for _, elem := range x {
+			if _, ok := elem.(ArrayValue); ok {
+				nested = true
+				continue
+			}
+			c.value(path, elem, depth+1)
+		}
+		if nested {
+			c.fail(path, ConstraintNestedArray, "is an array directly containing an array")
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package firestruct
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// nestedMap returns a map with fields nested depth levels deep, the innermost field is a string.
+func nestedMap(depth int) map[string]any {
+	m := map[string]any{"a": "leaf"}
+	for i := 1; i < depth; i++ {
+		m = map[string]any{"a": m}
+	}
+	return m
+}
+
+func TestCheckConstraints(t *testing.T) {
+	thisFunctionName := "CheckConstraints"
+	type post struct {
+		Title string         `firestore:"title"`
+		Grid  [][]int        `firestore:"grid"`
+		Meta  map[string]any `firestore:"meta"`
+	}
+
+	tests := []struct {
+		Name     string
+		Input    any
+		Expected []string
+	}{
+		{
+			Name:  "valid",
+			Input: map[string]any{"title": "Hello", "tags": []any{"a", map[string]any{"b": []any{1}}}, "deep": nestedMap(MaxFieldDepth - 1)},
+		},
+		{
+			Name:     "nested array",
+			Input:    &post{Title: "Hello", Grid: [][]int{{1}, {2}}},
+			Expected: []string{"grid:nestedArray"},
+		},
+		{
+			Name:     "names",
+			Input:    &post{Meta: map[string]any{"": 1, "__name__": 2, "_ok_": 3, strings.Repeat("x", MaxFieldNameSize+1): 4}},
+			Expected: []string{"meta.``:emptyName", "meta.__name__:reservedName", "meta." + strings.Repeat("x", MaxFieldNameSize+1) + ":nameSize"},
+		},
+		{
+			Name:     "depth",
+			Input:    map[string]any{"deep": nestedMap(MaxFieldDepth)["a"], "deeper": nestedMap(MaxFieldDepth + 1)["a"]},
+			Expected: []string{"deeper" + strings.Repeat(".a", MaxFieldDepth-1) + ":depth"},
+		},
+		{
+			Name:     "array depth",
+			Input:    map[string]any{"deep": []any{nestedMap(MaxFieldDepth - 2)}, "deeper": []any{nestedMap(MaxFieldDepth - 1)}},
+			Expected: []string{"deeper" + strings.Repeat(".a", MaxFieldDepth-2) + ":depth"},
+		},
+		{
+			Name: "vector",
+			Input: map[string]any{
+				"embedding": map[string]any{"__type__": "__vector__", "value": []any{0.5, 1.0}},
+				"vectors":   []any{VectorValue{1, 2}.Interface()},
+			},
+		},
+		{
+			Name:     "vector lookalike",
+			Input:    map[string]any{"embedding": map[string]any{"__type__": "__vector__", "value": []any{"a"}}},
+			Expected: []string{"embedding.__type__:reservedName"},
+		},
+		{
+			Name:     "sizes",
+			Input:    map[string]any{"a": strings.Repeat("x", MaxFieldValueSize), "b": []byte(strings.Repeat("x", 100))},
+			Expected: []string{"a:valueSize", ":documentSize"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := CheckConstraints(test.Input)
+
+			var result []string
+			var verrs ValidationErrors
+			if err != nil && !errors.As(err, &verrs) {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+			for _, e := range verrs {
+				result = append(result, e.Path.String()+":"+e.Rule)
+			}
+			if !reflect.DeepEqual(result, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%v\n%v", thisFunctionName, test.Name, result, test.Expected)
+			}
+		})
+	}
+
+	if err := CheckConstraints("foo"); err == nil {
+		t.Errorf("%v() test \"%v\" expected an error", thisFunctionName, "string")
+	}
+}
+
+func TestDocumentCheckConstraints(t *testing.T) {
+	thisFunctionName := "FirestoreDocument.CheckConstraints"
+	if err := taskDoc.CheckConstraints(); err != nil {
+		t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, "task", err)
+	}
+
+	doc := FirestoreDocument{Fields: map[string]any{
+		"__id__": map[string]any{"stringValue": "x"},
+	}}
+	err := doc.CheckConstraints()
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 1 || verrs[0].Rule != ConstraintReservedName {
+		t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, "reserved name", err)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DecodeAll unwraps and decodes docs into a slice of T in a pool of goroutines, see FirestoreDocument.DataTo.
+// The decoded values are in the order of docs. The pool has runtime.GOMAXPROCS goroutines unless WithConcurrency is used.
+//
+// Documents that fail to decode leave a zero T at their index, and their errors are returned in a *BatchError
+// along with the other decoded values. When ctx is done before every document was decoded, the remaining documents
+// are not decoded and ctx.Err() is returned.
+func DecodeAll[T any](ctx context.Context, docs []FirestoreDocument, opts ...DecodeOption) ([]T, error) {
+	o := newDecodeOptions(opts)
+	if o.err != nil {
+		return nil, o.err
+	}
+	workers := o.concurrency
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(docs) {
+		workers = len(docs)
+	}
+
+	out := make([]T, len(docs))
+	var (
+		wg      sync.WaitGroup
+		next    atomic.Int64 // index of the next document to decode
+		decoded atomic.Int64 // number of documents decoded, with or without an error
+		mu      sync.Mutex
+		errs    map[int]error
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if i >= len(docs) {
+					return
+				}
+				var v T
+				err := docs[i].DataTo(&v, opts...)
+				decoded.Add(1)
+				if err != nil {
+					mu.Lock()
+					if errs == nil {
+						errs = make(map[int]error)
+					}
+					errs[i] = err
+					mu.Unlock()
+					continue
+				}
+				out[i] = v
+			}
+		}()
+	}
+	wg.Wait()
+
+	// A context done after the last document was decoded does not discard the results
+	if int(decoded.Load()) < len(docs) {
+		return nil, ctx.Err()
+	}
+	if errs != nil {
+		return out, &BatchError{Errors: errs}
+	}
+	return out, nil
+}
+
+// WithConcurrency sets the number of goroutines DecodeAll decodes documents with, n <= 0 uses runtime.GOMAXPROCS.
+// It has no effect on other functions.
+func WithConcurrency(n int) DecodeOption {
+	return func(o *decodeOptions) {
+		o.concurrency = n
+	}
+}
+
+// A BatchError is returned by DecodeAll when some documents failed to decode.
+type BatchError struct {
+	Errors map[int]error // errors by index of the document
+}
+
+func (e *BatchError) Error() string {
+	indexes := e.indexes()
+	if len(indexes) == 0 {
+		return "DecodeAll error, no documents failed to decode"
+	}
+	first := indexes[0]
+	if len(indexes) == 1 {
+		return fmt.Sprintf("DecodeAll error, document %d: %v", first, e.Errors[first])
+	}
+	return fmt.Sprintf("DecodeAll error, %d documents failed to decode, first document %d: %v", len(indexes), first, e.Errors[first])
+}
+
+// Unwrap returns the errors of the documents in the order of their index, for use with errors.Is and errors.As.
+func (e *BatchError) Unwrap() []error {
+	indexes := e.indexes()
+	errs := make([]error, len(indexes))
+	for i, index := range indexes {
+		errs[i] = e.Errors[index]
+	}
+	return errs
+}
+
+// indexes returns the indexes of the documents that failed to decode, sorted.
+func (e *BatchError) indexes() []int {
+	indexes := make([]int, 0, len(e.Errors))
+	for i := range e.Errors {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
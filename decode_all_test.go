@@ -0,0 +1,207 @@
+package firestruct
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+type decodeAllTarget struct {
+	Name  string `firestore:"name"`
+	Count int    `firestore:"count"`
+}
+
+// decodeAllDocs returns n documents, the documents at the indexes in invalid have a count that does not decode.
+func decodeAllDocs(n int, invalid ...int) ([]FirestoreDocument, []decodeAllTarget) {
+	docs := make([]FirestoreDocument, n)
+	expected := make([]decodeAllTarget, n)
+	for i := range docs {
+		docs[i].Fields = map[string]any{
+			"name":  map[string]any{"stringValue": fmt.Sprintf("doc%d", i)},
+			"count": map[string]any{"integerValue": fmt.Sprint(i)},
+		}
+		expected[i] = decodeAllTarget{Name: fmt.Sprintf("doc%d", i), Count: i}
+	}
+	for _, i := range invalid {
+		docs[i].Fields["count"] = map[string]any{"stringValue": "x"}
+		expected[i] = decodeAllTarget{}
+	}
+	return docs, expected
+}
+
+func TestDecodeAll(t *testing.T) {
+	thisFunctionName := "DecodeAll"
+	tests := []struct {
+		Name    string
+		Docs    int
+		Invalid []int
+		Opts    []DecodeOption
+	}{
+		{Name: "no documents", Docs: 0},
+		{Name: "one document", Docs: 1},
+		{Name: "default concurrency", Docs: 100},
+		{Name: "one goroutine", Docs: 100, Opts: []DecodeOption{WithConcurrency(1)}},
+		{Name: "more goroutines than documents", Docs: 3, Opts: []DecodeOption{WithConcurrency(16)}},
+		{Name: "one error", Docs: 10, Invalid: []int{4}, Opts: []DecodeOption{WithConcurrency(4)}},
+		{Name: "errors", Docs: 100, Invalid: []int{0, 57, 99}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			docs, expected := decodeAllDocs(test.Docs, test.Invalid...)
+			result, err := DecodeAll[decodeAllTarget](context.Background(), docs, test.Opts...)
+			if len(test.Invalid) == 0 {
+				if err != nil {
+					t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+				}
+			} else {
+				var batchErr *BatchError
+				if !errors.As(err, &batchErr) {
+					t.Fatalf("%v() test \"%v\" did not return a BatchError: %v", thisFunctionName, test.Name, err)
+				}
+				indexes := batchErr.indexes()
+				if !reflect.DeepEqual(indexes, test.Invalid) || len(batchErr.Unwrap()) != len(test.Invalid) {
+					t.Errorf("%v() test \"%v\" returned errors for documents %v, expected %v", thisFunctionName, test.Name, indexes, test.Invalid)
+				}
+			}
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%+v\n%+v", thisFunctionName, test.Name, result, expected)
+			}
+		})
+	}
+}
+
+func TestDecodeAllErrors(t *testing.T) {
+	thisFunctionName := "DecodeAll"
+	docs, _ := decodeAllDocs(10, 3, 7)
+
+	_, err := DecodeAll[decodeAllTarget](context.Background(), docs)
+	expected := "DecodeAll error, 2 documents failed to decode, first document 3: "
+	if err == nil || len(err.Error()) < len(expected) || err.Error()[:len(expected)] != expected {
+		t.Errorf("%v() returned an unexpected error: %v", thisFunctionName, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result, err := DecodeAll[decodeAllTarget](ctx, docs); !errors.Is(err, context.Canceled) || result != nil {
+		t.Errorf("%v() with a cancelled context returned %v, %v", thisFunctionName, result, err)
+	}
+
+	if _, err := DecodeAll[decodeAllTarget](context.Background(), docs, WithFields("")); err == nil {
+		t.Errorf("%v() did not return the error of an invalid option", thisFunctionName)
+	}
+}
+
+func TestDecodeAllWithMigrations(t *testing.T) {
+	thisFunctionName := "DecodeAll"
+	reg := NewMigrationRegistry()
+	for _, collection := range []string{"users", "teams"} {
+		collection := collection
+		reg.Register(collection, 0, func(m map[string]any) (map[string]any, error) {
+			m["collection"] = collection
+			return m, nil
+		})
+	}
+
+	docs, _ := decodeAllDocs(1000)
+	for i := range docs {
+		collection := "users"
+		if i%2 == 1 {
+			collection = "teams"
+		}
+		docs[i].Name = fmt.Sprintf("projects/p/databases/(default)/documents/%s/doc%d", collection, i)
+	}
+
+	// Spare capacity lets an append to the options write to the slice shared by the goroutines
+	opts := make([]DecodeOption, 2, 8)
+	opts[0], opts[1] = WithMigrations(reg), WithConcurrency(8)
+	result, err := DecodeAll[struct {
+		Name       string `firestore:"name"`
+		Collection string `firestore:"collection"`
+	}](context.Background(), docs, opts...)
+	if err != nil {
+		t.Fatalf("%v() returned error: %v", thisFunctionName, err)
+	}
+	for i, r := range result {
+		if expected := Reference(docs[i].Name).CollectionID(); r.Collection != expected || r.Name != fmt.Sprintf("doc%d", i) {
+			t.Errorf("%v() document %d output does not match expected data:\n%+v\n%v", thisFunctionName, i, r, expected)
+		}
+	}
+	// The race detector may miss concurrent writes to the shared options, check that none happened
+	for i, opt := range opts[len(opts):cap(opts)] {
+		if opt != nil {
+			t.Errorf("%v() wrote to the spare capacity of the options at index %d", thisFunctionName, len(opts)+i)
+		}
+	}
+}
+
+// decodeAllHook is called by hookedTarget for every document it decodes.
+var decodeAllHook func()
+
+// hookedTarget calls decodeAllHook when it is decoded, e.g. to cancel DecodeAll part-way.
+type hookedTarget struct {
+	Name string `firestore:"name"`
+}
+
+func (h *hookedTarget) DecodeFirestore(fields map[string]any) error {
+	decodeAllHook()
+	return DecodeString(&h.Name, fields["name"])
+}
+
+func TestDecodeAllCancel(t *testing.T) {
+	thisFunctionName := "DecodeAll"
+	defer func() { decodeAllHook = nil }()
+	docs, _ := decodeAllDocs(10000)
+
+	tests := []struct {
+		Name        string
+		Concurrency int
+		CancelAt    int64 // number of decoded documents that cancels the context
+		Expected    error
+	}{
+		{Name: "part-way", Concurrency: 4, CancelAt: 100, Expected: context.Canceled},
+		{Name: "part-way with one goroutine", Concurrency: 1, CancelAt: 100, Expected: context.Canceled},
+		{Name: "after the last document", Concurrency: 1, CancelAt: int64(len(docs))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var calls atomic.Int64
+			decodeAllHook = func() {
+				if calls.Add(1) == test.CancelAt {
+					cancel()
+				}
+			}
+
+			result, err := DecodeAll[hookedTarget](ctx, docs, WithConcurrency(test.Concurrency))
+			if !errors.Is(err, test.Expected) {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+			if test.Expected == nil {
+				if len(result) != len(docs) || result[len(docs)-1].Name != "doc9999" {
+					t.Errorf("%v() test \"%v\" discarded the decoded documents", thisFunctionName, test.Name)
+				}
+				return
+			}
+			// workers busy when the context is cancelled finish their document, and then stop
+			if n := calls.Load(); n > test.CancelAt+int64(test.Concurrency) || result != nil {
+				t.Errorf("%v() test \"%v\" decoded %d documents after cancelling at %d", thisFunctionName, test.Name, n, test.CancelAt)
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeAll(b *testing.B) {
+	docs, _ := decodeAllDocs(1000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodeAll[decodeAllTarget](context.Background(), docs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
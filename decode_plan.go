@@ -0,0 +1,394 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/bennovw/firestruct/internal/fields"
+)
+
+// A decodeFunc uses data, which is never nil, to set p. It is compiled once for the type of p, see decodePlan.
+type decodeFunc func(d *decoder, p reflect.Value, data any) error
+
+// decodePlans caches the decodeFunc compiled for every Go type, like fieldCache caches their fields.
+var decodePlans sync.Map // map[reflect.Type]decodeFunc
+
+// decodePlan returns the decodeFunc of type t, compiling it on first use.
+func decodePlan(t reflect.Type) decodeFunc {
+	if f, ok := decodePlans.Load(t); ok {
+		return f.(decodeFunc)
+	}
+
+	// Store a placeholder waiting for the compiled plan first, so recursive types refer to it while it is compiled.
+	var (
+		wg   sync.WaitGroup
+		plan decodeFunc
+	)
+	wg.Add(1)
+	f, loaded := decodePlans.LoadOrStore(t, decodeFunc(func(d *decoder, p reflect.Value, data any) error {
+		wg.Wait()
+		return plan(d, p, data)
+	}))
+	if loaded {
+		return f.(decodeFunc)
+	}
+
+	plan = compileDecodePlan(t)
+	wg.Done()
+	decodePlans.Store(t, plan)
+	return plan
+}
+
+// compileDecodePlan returns the decodeFunc of type t.
+func compileDecodePlan(t reflect.Type) decodeFunc {
+	// Handle special types first.
+	switch t {
+	case typeOfByteSlice:
+		return decodeByteSlice
+	case typeOfGoTime:
+		return decodeGoTime
+	case typeOfLatLng:
+		return decodeLatLng
+	case typeOfUUID:
+		return decodeUUID
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return decodeBool
+	case reflect.String:
+		return decodeString
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return decodeInt
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return decodeUint
+	case reflect.Float32, reflect.Float64:
+		return decodeFloat
+	case reflect.Slice:
+		return compileSlicePlan(t)
+	case reflect.Array:
+		return compileArrayPlan(t)
+	case reflect.Map:
+		return compileMapPlan(t)
+	case reflect.Ptr:
+		return compilePtrPlan(t)
+	case reflect.Struct:
+		return compileStructPlan(t)
+	case reflect.Interface:
+		if t.NumMethod() == 0 { // empty interface
+			return decodeInterface
+		}
+	}
+	// Any other kind is an error.
+	return func(*decoder, reflect.Value, any) error {
+		return fmt.Errorf("cannot set type %s", t)
+	}
+}
+
+func compileSlicePlan(t reflect.Type) decodeFunc {
+	elem := decodePlan(t.Elem())
+	return func(d *decoder, p reflect.Value, data any) error {
+		vals, ok := data.([]any)
+		if !ok {
+			return typeErr(p, data)
+		}
+		vlen := p.Len()
+		xlen := len(vals)
+		if d.opts.merge && d.opts.slices == SliceAppend {
+			// Grow the slice and populate the appended elements only.
+			p.Set(reflect.AppendSlice(p, reflect.MakeSlice(t, xlen, xlen)))
+			return d.populateArray(p.Slice(vlen, vlen+xlen), vals, xlen, elem)
+		}
+		// Make a slice of the right size, avoiding allocation if possible.
+		switch {
+		case vlen < xlen:
+			p.Set(reflect.MakeSlice(t, xlen, xlen))
+		case vlen > xlen:
+			p.SetLen(xlen)
+		}
+		if d.opts.merge {
+			// Replacing a slice must not merge incoming elements into the existing ones.
+			z := reflect.Zero(t.Elem())
+			for i := 0; i < xlen; i++ {
+				p.Index(i).Set(z)
+			}
+		}
+		return d.populateArray(p, vals, xlen, elem)
+	}
+}
+
+func compileArrayPlan(t reflect.Type) decodeFunc {
+	elem := decodePlan(t.Elem())
+	return func(d *decoder, p reflect.Value, data any) error {
+		vals, ok := data.([]any)
+		if !ok {
+			return typeErr(p, data)
+		}
+
+		xlen := len(vals)
+		vlen := p.Len()
+		minlen := vlen
+		// Set extra elements to their zero value.
+		if vlen > xlen {
+			z := reflect.Zero(t.Elem())
+			for i := xlen; i < vlen; i++ {
+				p.Index(i).Set(z)
+			}
+			minlen = xlen
+		}
+		return d.populateArray(p, vals, minlen, elem)
+	}
+}
+
+func compileMapPlan(t reflect.Type) decodeFunc {
+	if t.Key().Kind() != reflect.String {
+		return func(*decoder, reflect.Value, any) error {
+			return errors.New("map key type is not string")
+		}
+	}
+	elem := decodePlan(t.Elem())
+	return func(d *decoder, p reflect.Value, data any) error {
+		x, ok := data.(map[string]any)
+		if !ok {
+			return typeErr(p, data)
+		}
+		return d.populateMap(p, x, elem)
+	}
+}
+
+func compilePtrPlan(t reflect.Type) decodeFunc {
+	elem := decodePlan(t.Elem())
+	return func(d *decoder, p reflect.Value, data any) error {
+		// If the pointer is nil, set it to a zero value.
+		if p.IsNil() {
+			p.Set(reflect.New(t.Elem()))
+		}
+		return elem(d, p.Elem(), data)
+	}
+}
+
+// populateArray sets the first n elements of vr, which must be a slice or
+// array, to the corresponding elements of vals using elem, the plan of the element type.
+func (d *decoder) populateArray(vr reflect.Value, vals []any, n int, elem decodeFunc) error {
+	for i := 0; i < n; i++ {
+		if err := d.decode(elem, vr.Index(i), vals[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// populateMap sets the elements of vm, which must be a map with string keys, from the
+// corresponding elements of pm using elem, the plan of the element type.
+//
+// Since a map value is not settable, this function always creates a new
+// element for each corresponding map key. Existing values of vm are
+// overwritten. This happens even if the map value is something like a pointer
+// to a struct, where we could in theory populate the existing struct value
+// instead of discarding it. This behavior matches encoding/json.
+//
+// In merge mode, a copy of the existing element is populated instead, so nested
+// maps and structs are merged rather than replaced.
+func (d *decoder) populateMap(vm reflect.Value, pm map[string]any, elem decodeFunc) error {
+	t := vm.Type()
+	if vm.IsNil() {
+		vm.Set(reflect.MakeMap(t))
+	}
+	// The element is copied into the map, so a single one is reused for every key.
+	el := reflect.New(t.Elem()).Elem()
+	for k, vproto := range pm {
+		key := reflect.ValueOf(k)
+		if key.Type() != t.Key() {
+			key = key.Convert(t.Key())
+		}
+		el.SetZero()
+		if d.opts.merge {
+			if existing := vm.MapIndex(key); existing.IsValid() {
+				el.Set(existing)
+			}
+		}
+		if err := d.decode(elem, el, vproto); err != nil {
+			return err
+		}
+		vm.SetMapIndex(key, el)
+	}
+	return nil
+}
+
+// A structPlan decodes maps into a struct type, with the lookups of its fields precomputed.
+type structPlan struct {
+	t        reflect.Type
+	fs       fields.List
+	plans    []decodeFunc    // plans of the field types, by index in fs
+	exact    map[string]int  // index in fs of the fields matched by their exact name, except the remain and metadata fields
+	remain   int             // index in fs of the remain field, -1 if there is none
+	defaults bool            // the struct has fields with a default tag option, or implements Defaulter
+	values   []reflect.Value // parsed default tag options, by index in fs, nil if there are none
+}
+
+func compileStructPlan(t reflect.Type) decodeFunc {
+	fs, err := fieldCache.Fields(t)
+	if err == nil {
+		_, err = remainField(t, fs)
+	}
+	var values []reflect.Value
+	if err == nil {
+		values, err = parseDefaults(t, fs)
+	}
+	if err != nil {
+		return func(*decoder, reflect.Value, any) error {
+			return err
+		}
+	}
+
+	s := &structPlan{
+		t:        t,
+		fs:       fs,
+		plans:    make([]decodeFunc, len(fs)),
+		exact:    make(map[string]int, len(fs)),
+		remain:   -1,
+		defaults: values != nil || t.Implements(typeOfDefaulter) || reflect.PtrTo(t).Implements(typeOfDefaulter),
+		values:   values,
+	}
+	for i := range fs {
+		s.plans[i] = decodePlan(fs[i].Type)
+		opts, _ := fs[i].ParsedTag.(tagOptions)
+		switch {
+		case opts.remain:
+			s.remain = i
+			continue
+		case opts.metadata != "":
+			continue
+		}
+		s.exact[fs[i].Name] = i
+	}
+	return s.decode
+}
+
+// decode sets the fields of vs, which must be a struct, from the matching elements of data.
+func (s *structPlan) decode(d *decoder, vs reflect.Value, data any) error {
+	x, ok := data.(map[string]any)
+	if !ok {
+		return typeErr(vs, data)
+	}
+
+	type match struct {
+		val any
+		i   int
+	}
+	seen := newFieldSet(len(s.fs))
+	var buf [16]match
+	folded := buf[:0]
+	var leftover map[string]any
+	for k, val := range x {
+		if i, ok := s.exact[k]; ok {
+			seen.add(i)
+			if err := s.decodeField(d, vs, i, val); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Case insensitive matches are decoded last, an exact match of the same field wins.
+		if f := matchField(s.fs, k); f != nil {
+			folded = append(folded, match{val: val, i: s.index(f)})
+			continue
+		}
+		if s.remain >= 0 {
+			if leftover == nil {
+				leftover = make(map[string]any)
+			}
+			leftover[k] = val
+		}
+	}
+
+	for _, m := range folded {
+		if seen.has(m.i) {
+			continue
+		}
+		seen.add(m.i)
+		if err := s.decodeField(d, vs, m.i, m.val); err != nil {
+			return err
+		}
+	}
+
+	if leftover != nil {
+		if err := s.decodeField(d, vs, s.remain, leftover); err != nil {
+			return err
+		}
+	}
+
+	// Defaults only apply when the whole document is decoded, fields outside a projection or merge are not missing
+	if s.defaults && d.opts.fields == nil && !d.opts.merge {
+		present := seen // copied so seen does not escape when there are no defaults
+		return d.populateDefaults(vs, s.fs, s.values, func(name string) bool {
+			i, ok := s.exact[name]
+			return ok && present.has(i)
+		})
+	}
+	return nil
+}
+
+// decodeField uses val to set the field of vs with index i in fs.
+func (s *structPlan) decodeField(d *decoder, vs reflect.Value, i int, val any) error {
+	f := &s.fs[i]
+	if err := d.decode(s.plans[i], fieldByIndex(vs, f.Index), val); err != nil {
+		return fmt.Errorf("%s.%s: %w", s.t, f.Name, err)
+	}
+	return nil
+}
+
+// index returns the index in fs of f, a field of fs.
+func (s *structPlan) index(f *fields.Field) int {
+	for i := range s.fs {
+		if &s.fs[i] == f {
+			return i
+		}
+	}
+	return s.exact[f.Name]
+}
+
+// A fieldSet holds the indexes of the struct fields populated from a document, without allocating for structs
+// of up to 64 fields.
+type fieldSet struct {
+	small uint64
+	large []bool
+}
+
+func newFieldSet(n int) fieldSet {
+	if n > 64 {
+		return fieldSet{large: make([]bool, n)}
+	}
+	return fieldSet{}
+}
+
+func (s *fieldSet) add(i int) {
+	if s.large != nil {
+		s.large[i] = true
+		return
+	}
+	s.small |= 1 << uint(i)
+}
+
+func (s *fieldSet) has(i int) bool {
+	if s.large != nil {
+		return s.large[i]
+	}
+	return s.small&(1<<uint(i)) != 0
+}
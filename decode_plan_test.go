@@ -0,0 +1,147 @@
+package firestruct
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bennovw/firestruct/internal/testutil"
+)
+
+type planNode struct {
+	Name     string      `firestore:"name"`
+	Children []*planNode `firestore:"children"`
+}
+
+type planCase struct {
+	Name  string
+	Title string
+}
+
+func TestDecodePlan(t *testing.T) {
+	thisFunctionName := "DataTo"
+
+	wide := reflect.StructOf(func() []reflect.StructField {
+		fs := make([]reflect.StructField, 70)
+		for i := range fs {
+			fs[i] = reflect.StructField{Name: fmt.Sprintf("F%d", i), Type: reflect.TypeOf(0)}
+		}
+		return fs
+	}())
+	wideExpected := reflect.New(wide).Elem()
+	wideExpected.Field(0).SetInt(1)
+	wideExpected.Field(69).SetInt(2)
+
+	tests := []struct {
+		Name     string
+		Input    map[string]any
+		Target   func() any
+		Expected any
+	}{
+		{
+			Name:     "recursive type",
+			Input:    map[string]any{"name": "root", "children": []any{map[string]any{"name": "leaf", "children": []any{}}}},
+			Target:   func() any { return &planNode{} },
+			Expected: &planNode{Name: "root", Children: []*planNode{{Name: "leaf"}}},
+		},
+		{
+			Name:     "case insensitive match",
+			Input:    map[string]any{"name": "a", "TITLE": "b"},
+			Target:   func() any { return &planCase{} },
+			Expected: &planCase{Name: "a", Title: "b"},
+		},
+		{
+			Name:     "exact match wins",
+			Input:    map[string]any{"name": "a", "Name": "b", "NAME": "c"},
+			Target:   func() any { return &planCase{} },
+			Expected: &planCase{Name: "b"},
+		},
+		{
+			Name:     "more than 64 fields",
+			Input:    map[string]any{"F0": 1, "f69": 2},
+			Target:   func() any { return reflect.New(wide).Interface() },
+			Expected: wideExpected.Addr().Interface(),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result := test.Target()
+			if err := DataTo(result, test.Input); err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+			if !reflect.DeepEqual(result, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%v\n%v", thisFunctionName, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
+
+func BenchmarkDataToSimpleStruct(b *testing.B) {
+	data, err := UnwrapFields(testutil.TestFirebaseDocFields[12])
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var result testutil.TestSimpleStruct
+		if err := DataTo(&result, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDataToTaggedStruct(b *testing.B) {
+	data, err := UnwrapFields(testutil.TestFirebaseDocFields[12])
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var result testutil.TestTaggedStruct
+		if err := DataTo(&result, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFirestoreDocumentDataTo(b *testing.B) {
+	doc := FirestoreDocument{Fields: testutil.TestFirebaseDocFields[12]}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var result testutil.TestTaggedStruct
+		if err := doc.DataTo(&result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDataToSlice(b *testing.B) {
+	type item struct {
+		Name  string  `firestore:"name"`
+		Count int     `firestore:"count"`
+		Score float64 `firestore:"score"`
+	}
+	items := make([]any, 100)
+	for i := range items {
+		items[i] = map[string]any{"name": "item", "count": i, "score": 0.5}
+	}
+	data := map[string]any{"items": items}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var result struct {
+			Items []item `firestore:"items"`
+		}
+		if err := DataTo(&result, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
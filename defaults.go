@@ -0,0 +1,179 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/bennovw/firestruct/internal/fields"
+)
+
+// A Defaulter provides default values for fields that are missing from a document, for defaults that cannot be
+// written in a default tag option, such as slices, maps and structs.
+//
+// Defaults returns the default values keyed by Firestore field name, matched exactly. Values assignable to the field
+// are assigned as is, other values are decoded like unwrapped document data, e.g. []any{"a", "b"} populates a []string
+// field.
+// Defaults returned by Defaults take precedence over default tag options.
+type Defaulter interface {
+	Defaults() map[string]any
+}
+
+var (
+	typeOfDefaulter = reflect.TypeOf((*Defaulter)(nil)).Elem()
+	typeOfDuration  = reflect.TypeOf(time.Duration(0))
+)
+
+// populateDefaults sets the fields of struct vs that are not present in the decoded map to their default value,
+// from the Defaulter implemented by the struct or from defaults, the parsed default tag options by index in fs.
+func (d *decoder) populateDefaults(vs reflect.Value, fs fields.List, defaults []reflect.Value, present func(name string) bool) error {
+	var custom map[string]any
+	switch {
+	case vs.CanAddr() && vs.Addr().Type().Implements(typeOfDefaulter):
+		custom = vs.Addr().Interface().(Defaulter).Defaults()
+	case vs.Type().Implements(typeOfDefaulter):
+		custom = vs.Interface().(Defaulter).Defaults()
+	}
+	for name := range custom {
+		// keys are matched exactly, a case insensitive match would be silently ignored
+		if f := fs.Match(name); f == nil || f.Name != name {
+			return fmt.Errorf("%s.Defaults: unknown field %q", vs.Type(), name)
+		}
+	}
+
+	for i := range fs {
+		f := &fs[i]
+		if opts, _ := f.ParsedTag.(tagOptions); present(f.Name) || opts.metadata != "" {
+			continue
+		}
+
+		if val, ok := custom[f.Name]; ok {
+			fv := fieldByIndex(vs, f.Index)
+			if rv := reflect.ValueOf(val); val != nil && rv.Type().AssignableTo(fv.Type()) {
+				fv.Set(rv)
+				continue
+			}
+			if err := d.dataToReflectPointer(fv, val); err != nil {
+				return fmt.Errorf("%s.%s: default: %w", vs.Type(), f.Name, err)
+			}
+			continue
+		}
+
+		if defaults != nil && defaults[i].IsValid() {
+			setDefault(fieldByIndex(vs, f.Index), defaults[i])
+		}
+	}
+	return nil
+}
+
+// parseDefaults parses the default tag options of fs, the fields of struct type t, by index in fs.
+// Fields without a default tag option have an invalid reflect.Value.
+func parseDefaults(t reflect.Type, fs fields.List) ([]reflect.Value, error) {
+	var defaults []reflect.Value
+	for i := range fs {
+		opts, _ := fs[i].ParsedTag.(tagOptions)
+		if !opts.hasDefault {
+			continue
+		}
+		v, err := parseDefault(fs[i].Type, opts.defaultValue)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: default: %w", t, fs[i].Name, err)
+		}
+		if defaults == nil {
+			defaults = make([]reflect.Value, len(fs))
+		}
+		defaults[i] = v
+	}
+	return defaults, nil
+}
+
+// parseDefault parses the value of a default tag option for a field of type t, pointers are parsed as their element.
+// Strings, booleans, numbers, time.Duration values such as "1h30m", time.Time values in RFC 3339 format,
+// and pointers to these types are supported.
+func parseDefault(t reflect.Type, s string) (reflect.Value, error) {
+	if t.Kind() == reflect.Ptr {
+		return parseDefault(t.Elem(), s)
+	}
+	v := reflect.New(t).Elem()
+
+	switch t {
+	case typeOfGoTime:
+		tm, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.Set(reflect.ValueOf(tm))
+		return v, nil
+
+	case typeOfDuration:
+		dur, err := time.ParseDuration(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetInt(int64(dur))
+		return v, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetFloat(f)
+
+	default:
+		return reflect.Value{}, fmt.Errorf("default tag option is not supported for type %s, implement Defaulter instead", t)
+	}
+	return v, nil
+}
+
+// setDefault sets v to def, a value parsed by parseDefault. Pointers are set to a new copy of def.
+func setDefault(v, def reflect.Value) {
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		setDefault(p.Elem(), def)
+		v.Set(p)
+		return
+	}
+	v.Set(def)
+}
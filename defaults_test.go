@@ -0,0 +1,122 @@
+package firestruct
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type defaultsTestStruct struct {
+	Name     string         `firestore:"name,default=anonymous"`
+	Active   bool           `firestore:"active,default=true"`
+	Retries  int8           `firestore:"retries,default=3"`
+	Ratio    float64        `firestore:"ratio,default=0.5"`
+	Timeout  time.Duration  `firestore:"timeout,default=1m30s"`
+	Since    time.Time      `firestore:"since,default=2025-04-14T01:02:03Z"`
+	Limit    *uint          `firestore:"limit,omitempty,default=10"`
+	Tags     []string       `firestore:"tags"`
+	Settings map[string]any `firestore:"settings"`
+}
+
+// Defaults provides the defaults that cannot be expressed in tags
+func (s *defaultsTestStruct) Defaults() map[string]any {
+	return map[string]any{
+		"tags":     []any{"new"},
+		"settings": map[string]any{"theme": "dark"},
+	}
+}
+
+func TestDataToDefaults(t *testing.T) {
+	thisFunctionName := "DataTo"
+	since, _ := time.Parse(time.RFC3339, "2025-04-14T01:02:03Z")
+	limit := uint(10)
+
+	tests := []struct {
+		Name     string
+		Input    map[string]any
+		Options  []DecodeOption
+		Expected defaultsTestStruct
+	}{
+		{
+			Name:  "missing fields",
+			Input: map[string]any{},
+			Expected: defaultsTestStruct{
+				Name: "anonymous", Active: true, Retries: 3, Ratio: 0.5, Timeout: 90 * time.Second, Since: since, Limit: &limit,
+				Tags: []string{"new"}, Settings: map[string]any{"theme": "dark"},
+			},
+		},
+		{
+			Name: "present fields",
+			Input: map[string]any{
+				"name":   map[string]any{"stringValue": "jane"},
+				"active": map[string]any{"booleanValue": false},
+				"limit":  map[string]any{"nullValue": nil},
+				"tags":   map[string]any{"arrayValue": map[string]any{"values": []any{map[string]any{"stringValue": "old"}}}},
+			},
+			Expected: defaultsTestStruct{
+				Name: "jane", Active: false, Retries: 3, Ratio: 0.5, Timeout: 90 * time.Second, Since: since,
+				Tags: []string{"old"}, Settings: map[string]any{"theme": "dark"},
+			},
+		},
+		{
+			Name:     "projection",
+			Input:    map[string]any{"name": map[string]any{"stringValue": "jane"}},
+			Options:  []DecodeOption{WithFields("name", "active")},
+			Expected: defaultsTestStruct{Name: "jane"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			doc := FirestoreDocument{Fields: test.Input}
+			var result defaultsTestStruct
+			if err := doc.DataTo(&result, test.Options...); err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+			if !reflect.DeepEqual(result, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%+v\n%+v", thisFunctionName, test.Name, result, test.Expected)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		Name   string
+		Target any
+	}{
+		{Name: "invalid number", Target: &struct {
+			N int8 `firestore:"n,default=300"`
+		}{}},
+		{Name: "invalid duration", Target: &struct {
+			D time.Duration `firestore:"d,default=soon"`
+		}{}},
+		{Name: "unsupported type", Target: &struct {
+			S []string `firestore:"s,default=a"`
+		}{}},
+	}
+	for _, test := range errorTests {
+		doc := FirestoreDocument{Fields: map[string]any{}}
+		if err := doc.DataTo(test.Target); err == nil {
+			t.Errorf("%v() test \"%v\" expected an error", thisFunctionName, test.Name)
+		}
+	}
+
+	// invalid default tag options fail every document, not only those missing the field
+	doc := FirestoreDocument{Fields: map[string]any{"n": map[string]any{"integerValue": "1"}}}
+	if err := doc.DataTo(errorTests[0].Target); err == nil {
+		t.Errorf("%v() with an invalid default of a present field expected an error", thisFunctionName)
+	}
+
+	// Defaults keys must match a field name exactly
+	var result defaultsCaseTestStruct
+	if err := (&FirestoreDocument{Fields: map[string]any{}}).DataTo(&result); err == nil {
+		t.Errorf("%v() with a Defaults key of a different case expected an error, got: %+v", thisFunctionName, result)
+	}
+}
+
+type defaultsCaseTestStruct struct {
+	Name string `firestore:"name"`
+}
+
+func (s *defaultsCaseTestStruct) Defaults() map[string]any {
+	return map[string]any{"NAME": "anonymous"}
+}
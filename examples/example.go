@@ -77,7 +77,7 @@ func main() {
 	//spew.Dump(s)
 
 	// Unwraps a protojson encoded Firestore document, outputs a flattened map[string]interface{}
-	uf, err := firestruct.UnwrapFirestoreFields(cloudEvent.Value.Fields)
+	uf, err := firestruct.UnwrapFields(cloudEvent.Value.Fields)
 	if err != nil {
 		fmt.Printf("Error unwrapping firestore data: %s", err)
 	}
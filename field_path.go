@@ -0,0 +1,263 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrFieldNotFound is returned when a field path does not exist in a document.
+var ErrFieldNotFound = errors.New("field not found")
+
+// A FieldPath is a non-empty sequence of field names that identifies a (possibly nested) field in a Firestore document.
+// Each element is a raw field name, dots and other special characters are not interpreted.
+type FieldPath []string
+
+// ParseFieldPath parses a dot separated Firestore field path such as "address.city".
+// Segments containing dots, spaces or other non-identifier characters must be quoted with backticks, e.g. "a.`b.c`.d",
+// backticks and backslashes inside a quoted segment are escaped with a backslash.
+// This is the syntax used by the field paths of an update mask.
+func ParseFieldPath(s string) (FieldPath, error) {
+	if s == "" {
+		return nil, errors.New("ParseFieldPath error, empty field path")
+	}
+
+	var fp FieldPath
+	for i := 0; i <= len(s); {
+		if i == len(s) {
+			// a trailing dot leaves an empty segment
+			return nil, fmt.Errorf("ParseFieldPath error, empty segment in field path %q", s)
+		}
+
+		var segment string
+		if s[i] == '`' {
+			seg, n, err := unquoteSegment(s[i:])
+			if err != nil {
+				return nil, fmt.Errorf("ParseFieldPath error in field path %q: %v", s, err)
+			}
+			segment = seg
+			i += n
+		} else {
+			end := strings.IndexByte(s[i:], '.')
+			if end == -1 {
+				end = len(s) - i
+			}
+			segment = s[i : i+end]
+			if segment == "" {
+				return nil, fmt.Errorf("ParseFieldPath error, empty segment in field path %q", s)
+			}
+			if strings.ContainsAny(segment, invalidUnquotedRunes) {
+				return nil, fmt.Errorf("ParseFieldPath error, segment %q in field path %q contains one of %q and must be quoted", segment, s, invalidUnquotedRunes)
+			}
+			i += end
+		}
+		fp = append(fp, segment)
+
+		if i == len(s) {
+			break
+		}
+		if s[i] != '.' {
+			return nil, fmt.Errorf("ParseFieldPath error, expecting '.' after segment %q in field path %q", segment, s)
+		}
+		i++
+	}
+
+	return fp, nil
+}
+
+// invalidUnquotedRunes lists the characters that are only permitted inside a backtick quoted segment.
+const invalidUnquotedRunes = "~*/[]`"
+
+// unquoteSegment reads a backtick quoted segment from the start of s and returns the unescaped field name and the number of bytes consumed.
+func unquoteSegment(s string) (string, int, error) {
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 == len(s) {
+				return "", 0, errors.New("unterminated escape sequence")
+			}
+			i++
+			sb.WriteByte(s[i])
+		case '`':
+			if sb.Len() == 0 {
+				return "", 0, errors.New("empty quoted segment")
+			}
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return "", 0, errors.New("unterminated backtick quoted segment")
+}
+
+// String returns the field path in Firestore syntax, quoting segments with backticks when required.
+func (fp FieldPath) String() string {
+	segments := make([]string, len(fp))
+	for i, name := range fp {
+		segments[i] = quoteSegment(name)
+	}
+	return strings.Join(segments, ".")
+}
+
+// quoteSegment returns name unchanged if it is a simple identifier, otherwise it returns name quoted with backticks.
+func quoteSegment(name string) string {
+	if isSimpleSegment(name) {
+		return name
+	}
+
+	var sb strings.Builder
+	sb.WriteByte('`')
+	for i := 0; i < len(name); i++ {
+		if name[i] == '`' || name[i] == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(name[i])
+	}
+	sb.WriteByte('`')
+	return sb.String()
+}
+
+// isSimpleSegment reports whether name matches [_a-zA-Z][_a-zA-Z0-9]* and can be used in a field path without quoting.
+func isSimpleSegment(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// GetPath returns the value stored at fp in an unwrapped map such as the output of ToMap, and whether it was found.
+func GetPath(m map[string]any, fp FieldPath) (any, bool) {
+	if len(fp) == 0 {
+		return nil, false
+	}
+
+	for _, name := range fp[:len(fp)-1] {
+		sub, ok := m[name].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		m = sub
+	}
+
+	v, ok := m[fp[len(fp)-1]]
+	return v, ok
+}
+
+// SetPath stores v at fp in an unwrapped map, creating intermediate maps as needed.
+// An error is returned if an intermediate field exists but does not contain a map.
+func SetPath(m map[string]any, fp FieldPath, v any) error {
+	if m == nil {
+		return errors.New("SetPath error, nil map")
+	}
+	if len(fp) == 0 {
+		return errors.New("SetPath error, empty field path")
+	}
+
+	for i, name := range fp[:len(fp)-1] {
+		existing, ok := m[name]
+		if !ok || existing == nil {
+			sub := make(map[string]any)
+			m[name] = sub
+			m = sub
+			continue
+		}
+
+		sub, ok := existing.(map[string]any)
+		if !ok {
+			return fmt.Errorf("SetPath error, field %s is a %T, not a map", fp[:i+1], existing)
+		}
+		if sub == nil {
+			// an empty Firestore map is unwrapped as a nil map, replace it so we can write to it
+			sub = make(map[string]any)
+			m[name] = sub
+		}
+		m = sub
+	}
+
+	m[fp[len(fp)-1]] = v
+	return nil
+}
+
+// DeletePath removes the value stored at fp from an unwrapped map and reports whether a value was removed.
+func DeletePath(m map[string]any, fp FieldPath) bool {
+	if len(fp) == 0 {
+		return false
+	}
+
+	for _, name := range fp[:len(fp)-1] {
+		sub, ok := m[name].(map[string]any)
+		if !ok {
+			return false
+		}
+		m = sub
+	}
+
+	if _, ok := m[fp[len(fp)-1]]; !ok {
+		return false
+	}
+	delete(m, fp[len(fp)-1])
+	return true
+}
+
+// lookupWrappedPath returns the protojson encoded value stored at fp in a map of Firestore document fields, without unwrapping it.
+// Intermediate segments must refer to Firestore maps.
+func lookupWrappedPath(fields map[string]any, fp FieldPath) (any, error) {
+	if len(fp) == 0 {
+		return nil, errors.New("empty field path")
+	}
+
+	for i, name := range fp {
+		val, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", fp, ErrFieldNotFound)
+		}
+		if i == len(fp)-1 {
+			return val, nil
+		}
+
+		wrapped, ok := val.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("field %s is not a valid Firestore value: %v", fp[:i+1], val)
+		}
+		mv, ok := wrapped[protoMapTag]
+		if !ok {
+			return nil, fmt.Errorf("%s: %w, field %s is not a map", fp, ErrFieldNotFound, fp[:i+1])
+		}
+		m, ok := mv.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("field %s is not a valid Firestore map: %v", fp[:i+1], mv)
+		}
+		sub, ok := m["fields"].(map[string]any)
+		if !ok {
+			// an empty Firestore map has no fields
+			return nil, fmt.Errorf("%s: %w", fp, ErrFieldNotFound)
+		}
+		fields = sub
+	}
+
+	return nil, fmt.Errorf("%s: %w", fp, ErrFieldNotFound)
+}
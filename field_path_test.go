@@ -0,0 +1,168 @@
+package firestruct
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bennovw/firestruct/internal/testutil"
+)
+
+type fieldPathTableTest struct {
+	Name     string
+	Input    string
+	Expected FieldPath
+	WantErr  bool
+}
+
+var fieldPathTests = []fieldPathTableTest{
+	{Name: "single segment", Input: "name", Expected: FieldPath{"name"}},
+	{Name: "nested segments", Input: "address.city", Expected: FieldPath{"address", "city"}},
+	{Name: "quoted segment with dot", Input: "a.`b.c`.d", Expected: FieldPath{"a", "b.c", "d"}},
+	{Name: "quoted segment with space", Input: "`first name`", Expected: FieldPath{"first name"}},
+	{Name: "escaped backtick and backslash", Input: "`a\\`b\\\\c`", Expected: FieldPath{"a`b\\c"}},
+	{Name: "quoted segment with digits", Input: "scores.`2024`", Expected: FieldPath{"scores", "2024"}},
+	{Name: "empty path", Input: "", WantErr: true},
+	{Name: "trailing dot", Input: "a.", WantErr: true},
+	{Name: "empty segment", Input: "a..b", WantErr: true},
+	{Name: "unterminated quote", Input: "a.`b", WantErr: true},
+	{Name: "empty quoted segment", Input: "``", WantErr: true},
+	{Name: "missing dot after quote", Input: "`a`b", WantErr: true},
+	{Name: "unquoted reserved character", Input: "a[0]", WantErr: true},
+}
+
+func TestParseFieldPath(t *testing.T) {
+	thisFunctionName := "ParseFieldPath"
+	for _, test := range fieldPathTests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := ParseFieldPath(test.Input)
+			if test.WantErr {
+				if err == nil {
+					t.Errorf("%v() test \"%v\" expected an error, got %v", thisFunctionName, test.Name, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+			if !testutil.Equal(result, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data: %v", thisFunctionName, test.Name, testutil.Diff(result, test.Expected))
+			}
+
+			// formatting the parsed path must round trip
+			reparsed, err := ParseFieldPath(result.String())
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error parsing formatted path %q: %v", thisFunctionName, test.Name, result.String(), err)
+			}
+			if !testutil.Equal(reparsed, test.Expected) {
+				t.Errorf("%v() test \"%v\" formatted path %q does not round trip", thisFunctionName, test.Name, result.String())
+			}
+		})
+	}
+}
+
+func TestFieldPathString(t *testing.T) {
+	thisFunctionName := "FieldPath.String"
+	tests := map[string]FieldPath{
+		"address.city": {"address", "city"},
+		"a.`b.c`.d":    {"a", "b.c", "d"},
+		"`first name`": {"first name"},
+		"_private.x1":  {"_private", "x1"},
+		"`1st`":        {"1st"},
+		"`a\\`b`":      {"a`b"},
+		"`café`.menu":  {"café", "menu"},
+	}
+	for expected, fp := range tests {
+		if result := fp.String(); result != expected {
+			t.Errorf("%v() test \"%v\" returned %q", thisFunctionName, expected, result)
+		}
+	}
+}
+
+func TestPathAccessors(t *testing.T) {
+	m := map[string]any{
+		"name": "Ghent",
+		"address": map[string]any{
+			"city": "Ghent",
+			"geo.location": map[string]any{
+				"lat": 51.05,
+			},
+		},
+		"empty": map[string]any(nil),
+	}
+
+	if v, ok := GetPath(m, FieldPath{"address", "geo.location", "lat"}); !ok || v != 51.05 {
+		t.Errorf("GetPath() returned %v, %v", v, ok)
+	}
+	if _, ok := GetPath(m, FieldPath{"name", "city"}); ok {
+		t.Errorf("GetPath() found a field nested below a string")
+	}
+	if _, ok := GetPath(m, FieldPath{"missing"}); ok {
+		t.Errorf("GetPath() found a missing field")
+	}
+
+	if err := SetPath(m, FieldPath{"address", "zip"}, "9000"); err != nil {
+		t.Errorf("SetPath() returned error: %v", err)
+	}
+	if err := SetPath(m, FieldPath{"new", "nested", "field"}, true); err != nil {
+		t.Errorf("SetPath() returned error: %v", err)
+	}
+	if err := SetPath(m, FieldPath{"empty", "field"}, 1); err != nil {
+		t.Errorf("SetPath() returned error: %v", err)
+	}
+	if err := SetPath(m, FieldPath{"name", "field"}, 1); err == nil {
+		t.Errorf("SetPath() expected an error when overwriting a string with a map")
+	}
+	if v, _ := GetPath(m, FieldPath{"new", "nested", "field"}); v != true {
+		t.Errorf("GetPath() returned %v after SetPath()", v)
+	}
+	if v, _ := GetPath(m, FieldPath{"empty", "field"}); v != 1 {
+		t.Errorf("GetPath() returned %v after SetPath() on an empty map", v)
+	}
+
+	if !DeletePath(m, FieldPath{"address", "city"}) {
+		t.Errorf("DeletePath() did not delete an existing field")
+	}
+	if DeletePath(m, FieldPath{"address", "city"}) {
+		t.Errorf("DeletePath() deleted a missing field")
+	}
+	if _, ok := GetPath(m, FieldPath{"address", "city"}); ok {
+		t.Errorf("GetPath() found a deleted field")
+	}
+}
+
+func TestFirestoreDocumentGet(t *testing.T) {
+	thisMethodName := "FirestoreDocument.Get"
+	doc := FirestoreDocument{Fields: testutil.TestFirebaseDocFields[12]}
+	expected := testutil.FlattenedMapResults[12]
+
+	tests := []struct {
+		Name     string
+		Path     FieldPath
+		Expected any
+	}{
+		{Name: "string", Path: FieldPath{"stringData"}, Expected: expected["stringData"]},
+		{Name: "string encoded integer", Path: FieldPath{"intData"}, Expected: expected["intData"]},
+		{Name: "timestamp", Path: FieldPath{"timeData"}, Expected: expected["timeData"]},
+		{Name: "map", Path: FieldPath{"nestedMapData"}, Expected: expected["nestedMapData"]},
+		{Name: "array nested in map", Path: FieldPath{"nestedMapData", "nestedArrayData"}, Expected: expected["nestedMapData"].(map[string]any)["nestedArrayData"]},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := doc.Get(test.Path)
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisMethodName, test.Name, err)
+			}
+			if !reflect.DeepEqual(result, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data", thisMethodName, test.Name)
+			}
+		})
+	}
+
+	for _, fp := range []FieldPath{{"missing"}, {"nestedMapData", "missing"}, {"stringData", "nested"}} {
+		if _, err := doc.Get(fp); !errors.Is(err, ErrFieldNotFound) {
+			t.Errorf("%v() test %q expected ErrFieldNotFound, got %v", thisMethodName, fp, err)
+		}
+	}
+}
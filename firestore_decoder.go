@@ -0,0 +1,318 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A FirestoreDecoder populates itself from unwrapped Firestore fields without reflection.
+// Implementations are generated by the firestruct-gen command, and follow the same rules as the reflective decoder.
+//
+// DataTo calls DecodeFirestore when its target implements FirestoreDecoder, unless merge mode is enabled.
+// Other options such as validation are still applied to the decoded target. A struct embedding a type with a generated
+// decoder inherits its DecodeFirestore method, which only populates the embedded fields, so it needs a generated decoder too.
+type FirestoreDecoder interface {
+	DecodeFirestore(fields map[string]any) error
+}
+
+// The functions below are used by code generated by firestruct-gen, they decode data into p exactly like DataTo
+// decodes data into a value of the same type. A nil data sets slices, maps, pointers and interfaces to nil,
+// and leaves other values unchanged.
+
+// DecodeBool decodes a boolean into p.
+func DecodeBool[T ~bool](p *T, data any) error {
+	if data == nil {
+		return nil
+	}
+	x, ok := data.(bool)
+	if !ok {
+		return decodeTypeErr(p, data)
+	}
+	*p = T(x)
+	return nil
+}
+
+// DecodeString decodes a string into p.
+func DecodeString[T ~string](p *T, data any) error {
+	if data == nil {
+		return nil
+	}
+	x, ok := data.(string)
+	if !ok {
+		return decodeTypeErr(p, data)
+	}
+	*p = T(x)
+	return nil
+}
+
+// DecodeInt decodes an integer into p, doubles are truncated.
+func DecodeInt[T ~int | ~int8 | ~int16 | ~int32 | ~int64](p *T, data any) error {
+	var i int64
+	switch x := data.(type) {
+	case nil:
+		return nil
+	case int:
+		i = int64(x)
+	case int8:
+		i = int64(x)
+	case int16:
+		i = int64(x)
+	case int32:
+		i = int64(x)
+	case int64:
+		i = x
+	case float64:
+		i = int64(x)
+	default:
+		return decodeTypeErr(p, data)
+	}
+
+	if int64(T(i)) != i {
+		return decodeOverflowErr(p, data)
+	}
+	*p = T(i)
+	return nil
+}
+
+// DecodeUint decodes an unsigned integer into p.
+func DecodeUint[T ~uint8 | ~uint16 | ~uint32](p *T, data any) error {
+	var u uint64
+	switch x := data.(type) {
+	case nil:
+		return nil
+	case uint8:
+		u = uint64(x)
+	case uint16:
+		u = uint64(x)
+	case uint32:
+		u = uint64(x)
+	case uint64:
+		u = x
+	default:
+		return decodeTypeErr(p, data)
+	}
+
+	if uint64(T(u)) != u {
+		return decodeOverflowErr(p, data)
+	}
+	*p = T(u)
+	return nil
+}
+
+// DecodeFloat decodes a double into p.
+func DecodeFloat[T ~float32 | ~float64](p *T, data any) error {
+	var f float64
+	switch x := data.(type) {
+	case nil:
+		return nil
+	case float32:
+		f = float64(x)
+	case float64:
+		f = x
+	default:
+		return decodeTypeErr(p, data)
+	}
+
+	// Like reflect.Value.OverflowFloat, finite doubles beyond the float32 range overflow, only float64 represents them exactly
+	if x := math.Abs(f); math.MaxFloat32 < x && x <= math.MaxFloat64 && float64(T(x)) != x {
+		return decodeOverflowErr(p, data)
+	}
+	*p = T(f)
+	return nil
+}
+
+// DecodeTime decodes a timestamp into p.
+func DecodeTime(p *time.Time, data any) error {
+	switch x := data.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		*p = x
+		return nil
+	case string:
+		t, err := time.Parse(time.RFC3339, x)
+		if err != nil {
+			return decodeTypeErr(p, data)
+		}
+		*p = t
+		return nil
+	}
+	return decodeTypeErr(p, data)
+}
+
+// DecodeBytes decodes bytes into p, base64 encoded strings are decoded.
+func DecodeBytes(p *[]byte, data any) error {
+	switch x := data.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case string:
+		b, err := base64.StdEncoding.DecodeString(x)
+		if err != nil {
+			return decodeTypeErr(p, data)
+		}
+		*p = b
+		return nil
+	case []byte:
+		*p = x
+		return nil
+	}
+	return decodeTypeErr(p, data)
+}
+
+// DecodeUUID decodes a string holding a UUID into p.
+func DecodeUUID(p *uuid.UUID, data any) error {
+	if data == nil {
+		return nil
+	}
+	x, ok := data.(string)
+	if !ok {
+		return decodeTypeErr(p, data)
+	}
+	id, err := uuid.Parse(x)
+	if err != nil {
+		return fmt.Errorf("%v is not a valid UUID: %v", data, err)
+	}
+	*p = id
+	return nil
+}
+
+// DecodeAny decodes any value into p. When p holds a pointer, the value it points to is decoded instead.
+func DecodeAny(p *any, data any) error {
+	if data == nil {
+		*p = nil
+		return nil
+	}
+	if *p != nil && reflect.TypeOf(*p).Kind() == reflect.Ptr {
+		return DecodeValue(*p, data)
+	}
+	*p = data
+	return nil
+}
+
+// DecodeSlice decodes an array into p, decoding its elements with elem. Existing elements are reused.
+func DecodeSlice[T any](p *[]T, data any, elem func(p *T, data any) error) error {
+	if data == nil {
+		*p = nil
+		return nil
+	}
+	vals, ok := data.([]any)
+	if !ok {
+		return decodeTypeErr(p, data)
+	}
+	// Make a slice of the right size, avoiding allocation if possible.
+	switch {
+	case len(*p) < len(vals):
+		*p = make([]T, len(vals))
+	case len(*p) > len(vals):
+		*p = (*p)[:len(vals)]
+	}
+	s := *p
+	for i, v := range vals {
+		if err := elem(&s[i], v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeMap decodes a map into p, decoding its values with elem. Existing values are replaced.
+func DecodeMap[K ~string, V any](p *map[K]V, data any, elem func(p *V, data any) error) error {
+	if data == nil {
+		*p = nil
+		return nil
+	}
+	x, ok := data.(map[string]any)
+	if !ok {
+		return decodeTypeErr(p, data)
+	}
+	if *p == nil {
+		*p = make(map[K]V, len(x))
+	}
+	m := *p
+	for k, v := range x {
+		var el V
+		if err := elem(&el, v); err != nil {
+			return err
+		}
+		m[K(k)] = el
+	}
+	return nil
+}
+
+// DecodePtr decodes data into the value p points to with elem, allocating it if p is nil.
+func DecodePtr[T any](p **T, data any, elem func(p *T, data any) error) error {
+	if data == nil {
+		*p = nil
+		return nil
+	}
+	if *p == nil {
+		*p = new(T)
+	}
+	return elem(*p, data)
+}
+
+// DecodeStruct decodes a map into p, a struct implementing FirestoreDecoder.
+func DecodeStruct[T any, PT interface {
+	*T
+	FirestoreDecoder
+}](p PT, data any) error {
+	if data == nil {
+		return nil
+	}
+	x, ok := data.(map[string]any)
+	if !ok {
+		return decodeTypeErr((*T)(p), data)
+	}
+	return p.DecodeFirestore(x)
+}
+
+// DecodeValue decodes data into the value p points to using reflection, for types without a specialized function.
+func DecodeValue(p any, data any) error {
+	pv := reflect.ValueOf(p)
+	if pv.Kind() != reflect.Ptr || pv.IsNil() {
+		return errors.New("target is nil or not a pointer")
+	}
+	return dataToReflectPointer(pv.Elem(), data)
+}
+
+// FoldField returns the index of the first name of names matching name case-insensitively, or -1 if none does.
+func FoldField(names []string, name string) int {
+	for i, n := range names {
+		if strings.EqualFold(n, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// decodeTypeErr returns the error of the reflective decoder for data that cannot populate the value p points to.
+func decodeTypeErr[T any](p *T, data any) error {
+	return fmt.Errorf("cannot use value %T to populate %T ", data, *p)
+}
+
+// decodeOverflowErr returns the error of the reflective decoder for data overflowing the value p points to.
+func decodeOverflowErr[T any](p *T, data any) error {
+	return fmt.Errorf("value %v overflows type %T", data, *p)
+}
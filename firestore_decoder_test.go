@@ -0,0 +1,54 @@
+package firestruct
+
+import (
+	"errors"
+	"testing"
+)
+
+// countingDecoder counts the calls of its decoder, and decodes the name field.
+type countingDecoder struct {
+	Name  string `firestore:"name"`
+	calls int
+}
+
+func (d *countingDecoder) DecodeFirestore(fields map[string]any) error {
+	d.calls++
+	return DecodeString(&d.Name, fields["name"])
+}
+
+type failingDecoder struct{}
+
+func (failingDecoder) DecodeFirestore(map[string]any) error {
+	return errors.New("failed")
+}
+
+func TestDataToFirestoreDecoder(t *testing.T) {
+	thisFunctionName := "DataTo"
+	tests := []struct {
+		Name  string
+		Input any
+		Opts  []DecodeOption
+		Calls int
+	}{
+		{Name: "default options", Input: map[string]any{"name": "a"}, Calls: 1},
+		{Name: "selected fields", Input: map[string]any{"name": "a"}, Opts: []DecodeOption{WithFields("name")}, Calls: 1},
+		{Name: "validation", Input: map[string]any{"name": "a"}, Opts: []DecodeOption{WithValidation()}, Calls: 1},
+		{Name: "merge", Input: map[string]any{"name": "a"}, Opts: []DecodeOption{WithMerge(SliceReplace)}, Calls: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var result countingDecoder
+			if err := DataTo(&result, test.Input, test.Opts...); err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+			if result.calls != test.Calls || result.Name != "a" {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%+v\n%v calls", thisFunctionName, test.Name, result, test.Calls)
+			}
+		})
+	}
+
+	if err := DataTo(&failingDecoder{}, map[string]any{}); err == nil || err.Error() != "failed" {
+		t.Errorf("%v() did not return the error of DecodeFirestore: %v", thisFunctionName, err)
+	}
+}
@@ -45,8 +45,9 @@ func (e *FirestoreCloudEvent) Document() *FirestoreDocument {
 // DataTo uses the current version of the Firestore document to populate p, which should be a pointer to a struct or a pointer to a map[string]interface{}.
 // You may add tags to your struct fields formatted as `firestore:"changeme"` to specify the Firestore field name to use. If you do not specify a tag, the field name will be used.
 // If the Firestore document contains a field that is not present in the struct, it will be ignored. If the struct contains a field that is not present in the Firestore document, it will be set to its zero value.
-func (e *FirestoreCloudEvent) DataTo(p interface{}) error {
-	return e.Value.DataTo(p)
+// Options such as WithFields can be used to only decode the fields listed in the event's update mask.
+func (e *FirestoreCloudEvent) DataTo(p interface{}, opts ...DecodeOption) error {
+	return e.Value.DataTo(p, opts...)
 }
 
 // ToMap returns the current version of the Firestore document as an unwrapped map[string]interface{} without any nested protojson type descriptor tags.
@@ -55,13 +56,20 @@ func (e *FirestoreCloudEvent) ToMap() (map[string]any, error) {
 	return m, err
 }
 
+// ToMapInPlace is like ToMap, but unwraps the fields of the current version of the document in place, see FirestoreDocument.ToMapInPlace.
+func (e *FirestoreCloudEvent) ToMapInPlace() (map[string]any, error) {
+	return e.Value.ToMapInPlace()
+}
+
 // A Firestore document.
 // Fields contains Firestore JSON encoded data types, see https://Firestore.google.com/docs/firestore/reference/rest/v1/Value
+// For compatibility with UnwrapFirestoreFields, the methods of FirestoreDocument also accept array elements that are the
+// fields of a map without a mapValue descriptor.
 type FirestoreDocument struct {
 	Name       string         `firestore:"name,omitempty" json:"name,omitempty"`
 	Fields     map[string]any `firestore:"fields,omitempty" json:"fields,omitempty"`
-	CreateTime time.Time      `firestore:"createTime,serverTimestamp,omitempty" json:"createTime,omitempty"`
-	UpdateTime time.Time      `firestore:"updateTime,serverTimestamp,omitempty" json:"updateTime,omitempty"`
+	CreateTime time.Time      `firestore:"createTime,omitempty" json:"createTime,omitempty"`
+	UpdateTime time.Time      `firestore:"updateTime,omitempty" json:"updateTime,omitempty"`
 }
 
 // DataTo uses the document's fields to populate p, which can be a pointer to a
@@ -96,34 +104,162 @@ type FirestoreDocument struct {
 // DocumentRef.Create.
 //
 // Only the fields actually present in the document are used to populate p. Other fields
-// of p are left unchanged.
-func (d *FirestoreDocument) DataTo(p interface{}) error {
+// of p are left unchanged, unless they have a default value. When the WithFields option is used,
+// only the selected fields are unwrapped and used to populate p. The WithMerge option deep merges
+// maps instead of replacing their values, and controls whether slices are replaced or appended to.
+// The WithMigrations option migrates the document to the latest schema version before populating p.
+// The WithLimits option bounds the resources used to unwrap and decode untrusted documents.
+//
+// Struct fields tagged with the docid, docname, createTime or updateTime option, e.g. `firestore:",docid"`,
+// are populated with the document's ID, name, create time and update time instead of its fields.
+func (d *FirestoreDocument) DataTo(p interface{}, opts ...DecodeOption) error {
+	o := newDecodeOptions(opts)
+	if o.err != nil {
+		return o.err
+	}
+
+	// Metadata is populated first, so it can be validated along with the fields
+	if err := d.populateMetadata(p); err != nil {
+		return err
+	}
+
+	u := &unwrapper{limits: o.limits, lenient: true}
+	if o.migrations != nil {
+		// Migrations need the whole document, fields are selected from the migrated document
+		flatDoc, err := d.toMap(u)
+		if err != nil {
+			return fmt.Errorf("error converting Firestore document to map %w", err)
+		}
+		collection := o.collection
+		if collection == "" {
+			collection = Reference(d.Name).CollectionID()
+		}
+		// opts is copied so the caller's slice, which may be shared by goroutines, is not written to
+		return DataTo(p, flatDoc, append(opts[:len(opts):len(opts)], WithCollection(collection))...)
+	}
+
+	if o.fields != nil {
+		if d == nil {
+			return errors.New("nil document contents")
+		}
+
+		// Only unwrap the selected fields
+		projected, missing, err := projectWrappedFields(d.Fields, o.fields, u)
+		if err != nil {
+			return fmt.Errorf("error converting Firestore document to map %w", err)
+		}
+		return dataTo(p, projected, o, missing)
+	}
+
 	// Remove Firestore protojson field tags from the document's fields.
-	flatDoc, err := d.ToMap()
+	flatDoc, err := d.toMap(u)
 	if err != nil {
-		return fmt.Errorf("error converting Firestore document to map %v", err)
+		return fmt.Errorf("error converting Firestore document to map %w", err)
 	}
 
-	return DataTo(p, flatDoc)
+	return dataTo(p, flatDoc, o, nil)
 }
 
 // ToMap converts a Firestore document to a native Go map[string]interface{} without protojson tags
 func (e *FirestoreDocument) ToMap() (map[string]any, error) {
+	return e.toMap(&unwrapper{lenient: true})
+}
+
+// ToMapInPlace is like ToMap, but consumes the document's Fields: their maps and arrays are unwrapped in place and
+// reused by the returned map, see UnwrapFieldsInPlace. Fields is set to nil, even when an error is returned.
+// Use it to unwrap large documents decoded by json.Unmarshal that are not needed afterwards.
+func (e *FirestoreDocument) ToMapInPlace() (map[string]any, error) {
+	if e == nil {
+		return nil, errors.New("nil document contents")
+	}
+
+	fields := e.Fields
+	e.Fields = nil
+	return (&unwrapper{inPlace: true, lenient: true}).unwrapFields(fields)
+}
+
+// toMap unwraps the document's fields with u.
+func (e *FirestoreDocument) toMap(u *unwrapper) (map[string]any, error) {
 	if e == nil {
 		return nil, errors.New("nil document contents")
 	}
 
-	fields, err := UnwrapFirestoreFields(e.Fields)
+	fields, err := u.unwrapFields(e.Fields)
 	if err != nil {
 		return nil, err
 	}
 	return fields, nil
 }
 
+// Get returns the unwrapped value of the field at fp without unwrapping the rest of the document.
+// An error wrapping ErrFieldNotFound is returned if the field does not exist.
+func (d *FirestoreDocument) Get(fp FieldPath) (any, error) {
+	if d == nil {
+		return nil, errors.New("nil document contents")
+	}
+
+	val, err := lookupWrappedPath(d.Fields, fp)
+	if err != nil {
+		return nil, err
+	}
+
+	return (&unwrapper{lenient: true}).unwrapValue(val, false)
+}
+
 // DataTo uses the input data to populate p, which can be a pointer to a struct or a pointer to a map[string]interface{}.
 // You may add tags to your struct fields formatted as `firestore:"changeme"` to specify the Firestore field name to use. If you do not specify a tag, the field name will be used.
 // If the input data contains a field that is not present in the struct, it will be ignored. If the struct contains a field that is not present in the input data, it will be set to its zero value.
-func DataTo(pointer interface{}, data any) error {
+// When the WithFields option is used, data must be a map[string]interface{} and only the selected fields are used to populate p.
+// When the WithLimits option is used, data is checked against the limits before it is used.
+func DataTo(pointer interface{}, data any, opts ...DecodeOption) error {
+	o := newDecodeOptions(opts)
+	if o.err != nil {
+		return o.err
+	}
+
+	if o.limits != nil {
+		if err := (&unwrapper{limits: o.limits}).checkUnwrapped(data); err != nil {
+			return err
+		}
+	}
+
+	if o.migrations != nil {
+		m, ok := data.(map[string]any)
+		if !ok {
+			return fmt.Errorf("cannot migrate %T, expecting a map[string]interface{}", data)
+		}
+		if o.collection == "" {
+			return errors.New("WithMigrations requires WithCollection when decoding a map")
+		}
+
+		migrated, err := o.migrations.Migrate(o.collection, m)
+		if err != nil {
+			return err
+		}
+		data = migrated
+	}
+
+	var missing []FieldPath
+	if o.fields != nil {
+		m, ok := data.(map[string]any)
+		if !ok {
+			return fmt.Errorf("cannot select fields from %T, expecting a map[string]interface{}", data)
+		}
+
+		projected, notFound, err := projectMap(m, o.fields)
+		if err != nil {
+			return err
+		}
+		data = projected
+		missing = notFound
+	}
+
+	return dataTo(pointer, data, o, missing)
+}
+
+// dataTo populates pointer with data using the decode options o.
+// The missing field paths were selected but not found in the data, they are zeroed when requested by o.
+func dataTo(pointer interface{}, data any, o *decodeOptions, missing []FieldPath) error {
 	pv := reflect.ValueOf(pointer)
 	if pv.Kind() != reflect.Ptr || pv.IsNil() {
 		return errors.New("target is nil or not a pointer to a struct or map")
@@ -136,6 +272,29 @@ func DataTo(pointer interface{}, data any) error {
 		return nil
 	}
 
-	// Otherwise, p is a pointer to a struct, so populate it recursively.
-	return dataToReflectPointer(pv.Elem(), data)
+	// Otherwise, p is a pointer to a struct, so populate it with its generated decoder or recursively.
+	// Generated decoders cannot merge into the existing contents of the target.
+	var err error
+	dec, generated := pointer.(FirestoreDecoder)
+	if m, ok := data.(map[string]any); ok && generated && !o.merge {
+		err = dec.DecodeFirestore(m)
+	} else {
+		err = newDecoder(o).dataToReflectPointer(pv.Elem(), data)
+	}
+	if err != nil {
+		return err
+	}
+
+	if o.zeroMissing {
+		for _, fp := range missing {
+			if err := zeroPath(pv.Elem(), fp); err != nil {
+				return err
+			}
+		}
+	}
+
+	if o.validate {
+		return Validate(pointer)
+	}
+	return nil
 }
@@ -0,0 +1,295 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// An ArrayIndexStyle determines how array indexes are written in the keys of a flat map.
+type ArrayIndexStyle int
+
+const (
+	// ArrayIndexBrackets writes array indexes in brackets, e.g. "tags[0]".
+	ArrayIndexBrackets ArrayIndexStyle = iota
+	// ArrayIndexDots writes array indexes as dot separated segments, e.g. "tags.0".
+	ArrayIndexDots
+)
+
+// A FlattenOption configures Flatten, Unflatten and ToFlatMap.
+type FlattenOption func(*flattenOptions)
+
+// flattenOptions holds the settings applied by FlattenOption functions.
+type flattenOptions struct {
+	arrayIndexStyle ArrayIndexStyle
+}
+
+// WithArrayIndexStyle sets the notation used for array indexes, ArrayIndexBrackets is used by default.
+func WithArrayIndexStyle(style ArrayIndexStyle) FlattenOption {
+	return func(o *flattenOptions) {
+		o.arrayIndexStyle = style
+	}
+}
+
+// newFlattenOptions applies opts to a new set of flatten options.
+func newFlattenOptions(opts []FlattenOption) *flattenOptions {
+	o := &flattenOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// ToFlatMap returns the current version of the Firestore document as a flat map, see FirestoreDocument.ToFlatMap.
+func (e *FirestoreCloudEvent) ToFlatMap(opts ...FlattenOption) (map[string]any, error) {
+	return e.Value.ToFlatMap(opts...)
+}
+
+// ToFlatMap converts a Firestore document to a flat map[string]interface{} with dot notation keys, such as
+// {"address.city": "Ghent", "tags[0]": "x"}. See Flatten for details.
+func (d *FirestoreDocument) ToFlatMap(opts ...FlattenOption) (map[string]any, error) {
+	m, err := d.ToMap()
+	if err != nil {
+		return nil, err
+	}
+
+	return Flatten(m, opts...), nil
+}
+
+// Flatten converts a nested unwrapped map, such as the output of UnwrapFields, into a flat map whose keys are field paths.
+// Nested map keys are joined with dots and quoted with backticks following the Firestore field path rules, e.g. "a.`b.c`",
+// array elements are indexed according to the ArrayIndexStyle option, e.g. "tags[0]".
+// Empty maps and arrays are kept as values so they survive a round trip through Unflatten.
+func Flatten(m map[string]any, opts ...FlattenOption) map[string]any {
+	o := newFlattenOptions(opts)
+	flat := make(map[string]any)
+	for k, v := range m {
+		flattenValue(flat, quoteSegment(k), v, o)
+	}
+	return flat
+}
+
+// flattenValue stores v under key in flat, or recursively stores its elements if v is a non-empty map or array.
+func flattenValue(flat map[string]any, key string, v any, o *flattenOptions) {
+	switch x := v.(type) {
+	case map[string]any:
+		if len(x) == 0 {
+			flat[key] = x
+			return
+		}
+		for k, sub := range x {
+			flattenValue(flat, key+"."+quoteSegment(k), sub, o)
+		}
+
+	case []any:
+		if len(x) == 0 {
+			flat[key] = x
+			return
+		}
+		for i, sub := range x {
+			flattenValue(flat, key+formatArrayIndex(i, o.arrayIndexStyle), sub, o)
+		}
+
+	default:
+		flat[key] = v
+	}
+}
+
+// formatArrayIndex returns the key suffix for array index i.
+func formatArrayIndex(i int, style ArrayIndexStyle) string {
+	if style == ArrayIndexDots {
+		return "." + strconv.Itoa(i)
+	}
+	return "[" + strconv.Itoa(i) + "]"
+}
+
+// Unflatten is the inverse of Flatten, it converts a flat map with field path keys back into a nested map.
+// The same ArrayIndexStyle option used to flatten the map must be used. Array elements missing from the flat map are set to nil.
+// An error is returned if a key cannot be parsed or if keys conflict, e.g. "a" holds a string while "a.b" implies a map.
+func Unflatten(flat map[string]any, opts ...FlattenOption) (map[string]any, error) {
+	o := newFlattenOptions(opts)
+
+	// Sort the keys so errors are deterministic
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var root any = make(map[string]any, len(flat))
+	for _, k := range keys {
+		steps, err := parseFlatKey(k, o.arrayIndexStyle)
+		if err != nil {
+			return nil, err
+		}
+		for _, st := range steps {
+			// every array element produces at least one key, so a larger index cannot be valid
+			if st.isIndex && st.index >= len(flat) {
+				return nil, fmt.Errorf("Unflatten error, array index %d in key %q is out of range", st.index, k)
+			}
+		}
+
+		root, err = unflattenInsert(root, steps, flat[k])
+		if err != nil {
+			return nil, fmt.Errorf("Unflatten error, key %q: %v", k, err)
+		}
+	}
+
+	return root.(map[string]any), nil
+}
+
+// A flatStep is a single step of a parsed flat map key, either a field name or an array index.
+type flatStep struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+// unflattenInsert stores v at the location described by steps below node, creating maps and arrays as needed, and returns the updated node.
+func unflattenInsert(node any, steps []flatStep, v any) (any, error) {
+	if len(steps) == 0 {
+		if node == nil {
+			return v, nil
+		}
+		// an empty map or array value may coexist with keys of its elements
+		if isEmptyContainer(v) {
+			return node, nil
+		}
+		if isEmptyContainer(node) {
+			return v, nil
+		}
+		return nil, errors.New("conflicting keys")
+	}
+
+	st := steps[0]
+	if st.isIndex {
+		arr, ok := node.([]any)
+		if node != nil && !ok {
+			return nil, fmt.Errorf("cannot index a %T", node)
+		}
+		for len(arr) <= st.index {
+			arr = append(arr, nil)
+		}
+		child, err := unflattenInsert(arr[st.index], steps[1:], v)
+		if err != nil {
+			return nil, err
+		}
+		arr[st.index] = child
+		return arr, nil
+	}
+
+	m, ok := node.(map[string]any)
+	if node != nil && !ok {
+		return nil, fmt.Errorf("cannot store field %q in a %T", st.name, node)
+	}
+	if m == nil {
+		m = make(map[string]any)
+	}
+	child, err := unflattenInsert(m[st.name], steps[1:], v)
+	if err != nil {
+		return nil, err
+	}
+	m[st.name] = child
+	return m, nil
+}
+
+// isEmptyContainer reports whether v is an empty unwrapped map or array.
+func isEmptyContainer(v any) bool {
+	switch x := v.(type) {
+	case map[string]any:
+		return len(x) == 0
+	case []any:
+		return len(x) == 0
+	}
+	return false
+}
+
+// parseFlatKey splits a flat map key into field names and array indexes.
+// Unquoted numeric segments are array indexes in the dot style, while the bracket style uses "[n]" suffixes.
+func parseFlatKey(key string, style ArrayIndexStyle) ([]flatStep, error) {
+	if key == "" {
+		return nil, errors.New("Unflatten error, empty key")
+	}
+
+	var steps []flatStep
+	i := 0
+	for {
+		if i == len(key) {
+			return nil, fmt.Errorf("Unflatten error, empty segment in key %q", key)
+		}
+
+		if key[i] == '`' {
+			name, n, err := unquoteSegment(key[i:])
+			if err != nil {
+				return nil, fmt.Errorf("Unflatten error in key %q: %v", key, err)
+			}
+			steps = append(steps, flatStep{name: name})
+			i += n
+		} else {
+			stop := ".`"
+			if style == ArrayIndexBrackets {
+				stop += "["
+			}
+			end := strings.IndexAny(key[i:], stop)
+			if end == -1 {
+				end = len(key) - i
+			}
+			segment := key[i : i+end]
+			if segment == "" {
+				return nil, fmt.Errorf("Unflatten error, empty segment in key %q", key)
+			}
+			if strings.ContainsAny(segment, invalidUnquotedRunes) {
+				return nil, fmt.Errorf("Unflatten error, segment %q in key %q must be quoted", segment, key)
+			}
+
+			if index, err := strconv.Atoi(segment); style == ArrayIndexDots && err == nil && len(steps) > 0 {
+				if index < 0 || segment[0] == '+' {
+					return nil, fmt.Errorf("Unflatten error, invalid array index %q in key %q", segment, key)
+				}
+				steps = append(steps, flatStep{index: index, isIndex: true})
+			} else {
+				steps = append(steps, flatStep{name: segment})
+			}
+			i += end
+		}
+
+		// Read bracketed array indexes following the segment
+		for style == ArrayIndexBrackets && i < len(key) && key[i] == '[' {
+			end := strings.IndexByte(key[i:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("Unflatten error, unterminated array index in key %q", key)
+			}
+			digits := key[i+1 : i+end]
+			index, err := strconv.Atoi(digits)
+			if err != nil || index < 0 || digits == "" || digits[0] == '+' || digits[0] == '-' {
+				return nil, fmt.Errorf("Unflatten error, invalid array index %q in key %q", digits, key)
+			}
+			steps = append(steps, flatStep{index: index, isIndex: true})
+			i += end + 1
+		}
+
+		if i == len(key) {
+			return steps, nil
+		}
+		if key[i] != '.' {
+			return nil, fmt.Errorf("Unflatten error, unexpected character %q in key %q", key[i], key)
+		}
+		i++
+	}
+}
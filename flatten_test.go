@@ -0,0 +1,148 @@
+package firestruct
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bennovw/firestruct/internal/testutil"
+)
+
+var flattenInput = map[string]any{
+	"name": "Jane",
+	"address": map[string]any{
+		"city":     "Ghent",
+		"zip code": "9000",
+	},
+	"tags": []any{"x", []any{"y", map[string]any{"z": true}}},
+	"a.b":  1,
+	"empty": map[string]any{
+		"map":   map[string]any{},
+		"array": []any{},
+	},
+	"nil": nil,
+}
+
+func TestFlatten(t *testing.T) {
+	thisFunctionName := "Flatten"
+	tests := []struct {
+		Name     string
+		Style    ArrayIndexStyle
+		Expected map[string]any
+	}{
+		{
+			Name:  "brackets",
+			Style: ArrayIndexBrackets,
+			Expected: map[string]any{
+				"name":               "Jane",
+				"address.city":       "Ghent",
+				"address.`zip code`": "9000",
+				"tags[0]":            "x",
+				"tags[1][0]":         "y",
+				"tags[1][1].z":       true,
+				"`a.b`":              1,
+				"empty.map":          map[string]any{},
+				"empty.array":        []any{},
+				"nil":                nil,
+			},
+		},
+		{
+			Name:  "dots",
+			Style: ArrayIndexDots,
+			Expected: map[string]any{
+				"name":               "Jane",
+				"address.city":       "Ghent",
+				"address.`zip code`": "9000",
+				"tags.0":             "x",
+				"tags.1.0":           "y",
+				"tags.1.1.z":         true,
+				"`a.b`":              1,
+				"empty.map":          map[string]any{},
+				"empty.array":        []any{},
+				"nil":                nil,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result := Flatten(flattenInput, WithArrayIndexStyle(test.Style))
+			if !reflect.DeepEqual(result, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data: %v", thisFunctionName, test.Name, result)
+			}
+
+			unflattened, err := Unflatten(result, WithArrayIndexStyle(test.Style))
+			if err != nil {
+				t.Errorf("Unflatten() test \"%v\" returned error: %v", test.Name, err)
+			}
+			if !reflect.DeepEqual(unflattened, flattenInput) {
+				t.Errorf("Unflatten() test \"%v\" does not round trip: %v", test.Name, unflattened)
+			}
+		})
+	}
+}
+
+func TestFlattenRoundTrip(t *testing.T) {
+	thisFunctionName := "Flatten"
+	for _, test := range firestoreUnwrapTests {
+		t.Run(test.Name, func(t *testing.T) {
+			for _, style := range []ArrayIndexStyle{ArrayIndexBrackets, ArrayIndexDots} {
+				flat := Flatten(test.Expected, WithArrayIndexStyle(style))
+				for k, v := range flat {
+					if _, isMap := v.(map[string]any); isMap && !isEmptyContainer(v) {
+						t.Errorf("%v() test \"%v\" key %q contains a nested map", thisFunctionName, test.Name, k)
+					}
+				}
+
+				result, err := Unflatten(flat, WithArrayIndexStyle(style))
+				if err != nil {
+					t.Errorf("Unflatten() test \"%v\" returned error: %v", test.Name, err)
+				}
+				testutil.IsDeepEqualTest(t, result, test.Expected, "Unflatten", test.Name)
+			}
+		})
+	}
+}
+
+func TestUnflattenErrors(t *testing.T) {
+	tests := map[string]map[string]any{
+		"conflicting scalar and map": {"a": 1, "a.b": 2},
+		"conflicting map and array":  {"a.b": 1, "a[0]": 2},
+		"empty segment":              {"a..b": 1},
+		"unterminated quote":         {"`a": 1},
+		"invalid index":              {"a[x]": 1},
+		"negative index":             {"a[-1]": 1},
+		"unterminated index":         {"a[0": 1},
+		"index out of range":         {"a[5]": 1},
+		"unquoted special character": {"a]": 1},
+	}
+	for name, input := range tests {
+		if result, err := Unflatten(input); err == nil {
+			t.Errorf("Unflatten() test \"%v\" expected an error, got %v", name, result)
+		}
+	}
+
+	// missing array elements are nil
+	result, err := Unflatten(map[string]any{"a[2]": 1, "b": 2, "c": 3})
+	if err != nil {
+		t.Errorf("Unflatten() test \"sparse array\" returned error: %v", err)
+	}
+	if !reflect.DeepEqual(result["a"], []any{nil, nil, 1}) {
+		t.Errorf("Unflatten() test \"sparse array\" output does not match expected data: %v", result)
+	}
+}
+
+func TestFirestoreCloudEventToFlatMap(t *testing.T) {
+	thisMethodName := "FirestoreCloudEvent.ToFlatMap"
+	event := FirestoreCloudEvent{Value: FirestoreDocument{Fields: testutil.TestFirebaseDocFields[12]}}
+
+	result, err := event.ToFlatMap()
+	if err != nil {
+		t.Errorf("%v() returned error: %v", thisMethodName, err)
+	}
+	if result["nestedMapData.nestedArrayData[1].subNestedArrayData[2].uuidData"] != "1f117a40-8bdb-4e8a-8f24-1622fea695b1" {
+		t.Errorf("%v() output does not match expected data: %v", thisMethodName, result)
+	}
+	if result["intData"] != 987654321 {
+		t.Errorf("%v() output does not match expected data: %v", thisMethodName, result["intData"])
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	ts "github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/google/uuid"
+
+	"github.com/bennovw/firestruct/internal/fields"
+)
+
+var (
+	typeOfValue     = reflect.TypeOf((*Value)(nil)).Elem()
+	typeOfReference = reflect.TypeOf(Reference(""))
+)
+
+// ValueOf converts a Go value into a typed Value, the reverse of DataTo.
+// Structs are converted to maps using the same struct tags as DataTo: fields are named by their tag, omitempty fields
+// with an empty value are left out, the fields of embedded and inlined structs are flattened into the map,
+// the entries of the remain map are added to the map, and metadata fields are left out.
+//
+// Nil pointers, slices and maps are converted to NullValue, Reference to ReferenceValue, uuid.UUID to StringValue,
+// and time.Time to TimestampValue. Values are returned as is.
+func ValueOf(v any) (Value, error) {
+	return valueOf(reflect.ValueOf(v))
+}
+
+// valueOf converts the Go value v into a typed Value.
+func valueOf(v reflect.Value) (Value, error) {
+	if !v.IsValid() {
+		return NullValue{}, nil
+	}
+
+	t := v.Type()
+	if t.Implements(typeOfValue) && (t.Kind() != reflect.Interface || !v.IsNil()) {
+		return v.Interface().(Value), nil
+	}
+
+	// Handle special types first.
+	switch t {
+	case typeOfByteSlice:
+		if v.IsNil() {
+			return NullValue{}, nil
+		}
+		return BytesValue(v.Bytes()), nil
+	case typeOfGoTime:
+		return TimestampValue(v.Interface().(time.Time)), nil
+	case typeOfLatLng:
+		return GeoPointValue{Latitude: v.FieldByName("Latitude").Float(), Longitude: v.FieldByName("Longitude").Float()}, nil
+	case typeOfProtoTimestamp:
+		if v.IsNil() {
+			return NullValue{}, nil
+		}
+		return TimestampValue(v.Interface().(*ts.Timestamp).AsTime()), nil
+	case typeOfUUID:
+		return StringValue(v.Interface().(uuid.UUID).String()), nil
+	case typeOfReference:
+		return ReferenceValue(v.String()), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return NullValue{}, nil
+		}
+		return valueOf(v.Elem())
+
+	case reflect.Bool:
+		return BoolValue(v.Bool()), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return IntegerValue(v.Int()), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("value %d of type %s overflows a Firestore integer", u, t)
+		}
+		return IntegerValue(u), nil
+
+	case reflect.Float32, reflect.Float64:
+		return DoubleValue(v.Float()), nil
+
+	case reflect.String:
+		return StringValue(v.String()), nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return NullValue{}, nil
+		}
+		arr := make(ArrayValue, v.Len())
+		for i := range arr {
+			x, err := valueOf(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = x
+		}
+		return arr, nil
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot convert %s, map keys must be strings", t)
+		}
+		if v.IsNil() {
+			return NullValue{}, nil
+		}
+		m := make(MapValue, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			x, err := valueOf(iter.Value())
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", quoteSegment(iter.Key().String()), err)
+			}
+			m[iter.Key().String()] = x
+		}
+		return m, nil
+
+	case reflect.Struct:
+		return structValue(v)
+	}
+
+	return nil, fmt.Errorf("cannot convert value of type %s to a Firestore value", t)
+}
+
+// structValue converts a struct into a MapValue using its struct tags.
+func structValue(v reflect.Value) (MapValue, error) {
+	fs, err := fieldCache.Fields(v.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(MapValue, len(fs))
+	var remain reflect.Value
+	for i := range fs {
+		f := &fs[i]
+		fv, err := v.FieldByIndexErr(f.Index)
+		if err != nil {
+			// a field of a nil embedded struct pointer
+			continue
+		}
+
+		opts, _ := f.ParsedTag.(tagOptions)
+		switch {
+		case opts.metadata != "":
+			continue
+		case opts.remain:
+			remain = fv
+			continue
+		case opts.omitEmpty && fields.IsEmptyValue(fv):
+			continue
+		}
+
+		x, err := valueOf(fv)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", v.Type(), f.Name, err)
+		}
+		m[f.Name] = x
+	}
+
+	if remain.IsValid() && remain.Kind() == reflect.Map {
+		iter := remain.MapRange()
+		for iter.Next() {
+			k := iter.Key().String()
+			if _, ok := m[k]; ok {
+				// fields of the struct take precedence
+				continue
+			}
+			x, err := valueOf(iter.Value())
+			if err != nil {
+				return nil, fmt.Errorf("%s: field %s: %w", v.Type(), quoteSegment(k), err)
+			}
+			m[k] = x
+		}
+	}
+	return m, nil
+}
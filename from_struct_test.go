@@ -0,0 +1,109 @@
+package firestruct
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bennovw/firestruct/internal/testutil"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+func TestValueOf(t *testing.T) {
+	thisFunctionName := "ValueOf"
+	type inner struct {
+		City string `firestore:"city"`
+	}
+	type doc struct {
+		ID       string            `firestore:",docid"`
+		Name     string            `firestore:"name"`
+		Nickname string            `firestore:"nickname,omitempty"`
+		Age      uint8             `firestore:"age"`
+		Place    *latlng.LatLng    `firestore:"place"`
+		Owner    Reference         `firestore:"owner"`
+		Address  inner             `firestore:",inline"`
+		Tags     []string          `firestore:"tags"`
+		Scores   map[string]int    `firestore:"scores,omitempty"`
+		Extra    map[string]any    `firestore:",remain"`
+		Raw      Value             `firestore:"raw"`
+		Skipped  string            `firestore:"-"`
+		Labels   map[string]string `firestore:"labels"`
+	}
+
+	tests := []struct {
+		Name     string
+		Input    any
+		Expected Value
+	}{
+		{
+			Name: "tagged struct",
+			Input: &doc{
+				ID:      "jane",
+				Name:    "Jane",
+				Age:     42,
+				Place:   &latlng.LatLng{Latitude: 1, Longitude: 2},
+				Owner:   "projects/p/databases/(default)/documents/users/joe",
+				Address: inner{City: "Ghent"},
+				Extra:   map[string]any{"name": "ignored", "legacy": true},
+				Raw:     IntegerValue(7),
+				Skipped: "skipped",
+			},
+			Expected: MapValue{
+				"name":   StringValue("Jane"),
+				"age":    IntegerValue(42),
+				"place":  GeoPointValue{Latitude: 1, Longitude: 2},
+				"owner":  ReferenceValue("projects/p/databases/(default)/documents/users/joe"),
+				"city":   StringValue("Ghent"),
+				"tags":   NullValue{},
+				"legacy": BoolValue(true),
+				"raw":    IntegerValue(7),
+				"labels": NullValue{},
+			},
+		},
+		{
+			Name:     "array",
+			Input:    [2]float32{0.5, 1},
+			Expected: ArrayValue{DoubleValue(0.5), DoubleValue(1)},
+		},
+		{
+			Name:     "nil",
+			Input:    nil,
+			Expected: NullValue{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := ValueOf(test.Input)
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+			if !reflect.DeepEqual(result, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%#v\n%#v", thisFunctionName, test.Name, result, test.Expected)
+			}
+		})
+	}
+
+	if _, err := ValueOf(map[string]uint64{"big": 1 << 63}); err == nil {
+		t.Errorf("%v() test \"%v\" expected an error", thisFunctionName, "overflow")
+	}
+}
+
+func TestValueOfRoundTrip(t *testing.T) {
+	thisFunctionName := "ValueOf"
+	for _, test := range firestoreToStructTests {
+		t.Run(test.Name, func(t *testing.T) {
+			v, err := ValueOf(test.Expected)
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+				return
+			}
+
+			doc := FirestoreDocument{Fields: WrapFields(v.(MapValue))}
+			result := reflect.New(reflect.TypeOf(test.Expected))
+			if err := doc.DataTo(result.Interface()); err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+			testutil.IsDeepEqualTest(t, result.Elem().Interface(), test.Expected, thisFunctionName, test.Name)
+		})
+	}
+}
@@ -0,0 +1,357 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A GenerateOption configures GenerateStruct.
+type GenerateOption func(*generateOptions)
+
+type generateOptions struct {
+	packageName string
+}
+
+// WithPackageName makes GenerateStruct return a complete Go source file in the named package, including the imports
+// required by the generated types. By default only the type declarations are returned.
+func WithPackageName(name string) GenerateOption {
+	return func(o *generateOptions) {
+		o.packageName = name
+	}
+}
+
+// GenerateStruct infers a Go struct type named name from one or more sample documents, given as their Firestore protojson
+// encoded fields, and returns its gofmt formatted source code.
+//
+// Firestore data types are mapped to Go types the way DataTo decodes them: timestampValue to time.Time,
+// geoPointValue to latlng.LatLng, referenceValue to Reference, integerValue to int64 and doubleValue to float64.
+// Fields that hold both integers and doubles become float64, fields that are null in some samples become pointers and
+// fields holding values of different types become any. Nested maps are declared as separate named structs and arrays become
+// slices of their element type, or []any when the elements have different types.
+// Fields missing from some samples are tagged omitempty.
+func GenerateStruct(name string, samples []map[string]any, opts ...GenerateOption) ([]byte, error) {
+	o := generateOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if !token.IsIdentifier(name) {
+		return nil, fmt.Errorf("invalid struct name: %q", name)
+	}
+	if len(samples) == 0 {
+		return nil, errors.New("at least one sample document is required")
+	}
+
+	root := newFieldShape()
+	for _, fields := range samples {
+		if err := root.observeMap(fields); err != nil {
+			return nil, err
+		}
+	}
+
+	g := structGenerator{
+		opts:    o,
+		names:   map[string]bool{},
+		imports: map[string]bool{},
+	}
+	comment := fmt.Sprintf("// %s was generated from %d sample Firestore documents.", name, len(samples))
+	if len(samples) == 1 {
+		comment = fmt.Sprintf("// %s was generated from a sample Firestore document.", name)
+	}
+	g.declare(name, root, comment)
+	var body bytes.Buffer
+	for len(g.pending) > 0 {
+		next := g.pending[0]
+		g.pending = g.pending[1:]
+		g.writeStruct(&body, next)
+	}
+
+	var src bytes.Buffer
+	if o.packageName != "" {
+		fmt.Fprintf(&src, "// Code generated by firestruct. DO NOT EDIT.\n\npackage %s\n\n", o.packageName)
+		if len(g.imports) > 0 {
+			src.WriteString("import (\n")
+			std := true
+			for _, path := range sortedImports(g.imports) {
+				if std && strings.Contains(path, ".") {
+					// Third-party imports are grouped after the standard library
+					src.WriteString("\n")
+					std = false
+				}
+				fmt.Fprintf(&src, "\t%q\n", path)
+			}
+			src.WriteString(")\n\n")
+		}
+	}
+	src.Write(bytes.TrimRight(body.Bytes(), "\n"))
+	src.WriteByte('\n')
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("error formatting generated source: %v", err)
+	}
+	return formatted, nil
+}
+
+// A fieldShape records the Firestore data types observed for a field across sample documents.
+type fieldShape struct {
+	tags     map[string]bool        // protojson type descriptor tags observed, except nullValue
+	nullable bool                   // whether the field was null in any sample
+	present  int                    // number of observed parent maps containing the field
+	maps     int                    // number of observed mapValues
+	fields   map[string]*fieldShape // fields of observed mapValues
+	elem     *fieldShape            // elements of observed arrayValues
+}
+
+func newFieldShape() *fieldShape {
+	return &fieldShape{tags: map[string]bool{}, fields: map[string]*fieldShape{}}
+}
+
+// observe records the type of a single Firestore protojson encoded value.
+func (s *fieldShape) observe(value any) error {
+	tag, inner, err := splitWrapped(value, false)
+	if err != nil {
+		return err
+	}
+
+	switch tag {
+	case protoNullTag:
+		s.nullable = true
+	case protoMapTag:
+		m, _ := inner.(map[string]any)
+		fields, _ := m["fields"].(map[string]any)
+		return s.observeMap(fields)
+	case protoArrayTag:
+		s.tags[tag] = true
+		if s.elem == nil {
+			s.elem = newFieldShape()
+		}
+		m, _ := inner.(map[string]any)
+		values, _ := m["values"].([]any)
+		for _, v := range values {
+			if err := s.elem.observe(v); err != nil {
+				return err
+			}
+		}
+	default:
+		s.tags[tag] = true
+	}
+	return nil
+}
+
+// observeMap records the types of the fields of a map.
+func (s *fieldShape) observeMap(fields map[string]any) error {
+	s.tags[protoMapTag] = true
+	s.maps++
+	for name, v := range fields {
+		f, ok := s.fields[name]
+		if !ok {
+			f = newFieldShape()
+			s.fields[name] = f
+		}
+		f.present++
+		if err := f.observe(v); err != nil {
+			return fmt.Errorf("field %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// A pendingStruct is a struct type that still needs to be written.
+type pendingStruct struct {
+	name    string
+	shape   *fieldShape
+	comment string
+}
+
+type structGenerator struct {
+	opts    generateOptions
+	names   map[string]bool // declared type names
+	imports map[string]bool // import paths used by the generated types
+	pending []pendingStruct
+}
+
+// declare reserves a unique type name for a struct of the given shape and queues it to be written.
+func (g *structGenerator) declare(name string, shape *fieldShape, comment string) string {
+	unique := name
+	for i := 2; g.names[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	g.names[unique] = true
+	if unique != name {
+		comment = strings.Replace(comment, name, unique, 1)
+	}
+	g.pending = append(g.pending, pendingStruct{name: unique, shape: shape, comment: comment})
+	return unique
+}
+
+func (g *structGenerator) writeStruct(buf *bytes.Buffer, s pendingStruct) {
+	fmt.Fprintf(buf, "%s\ntype %s struct {\n", s.comment, s.name)
+
+	goNames := map[string]bool{}
+	for _, name := range sortedKeys(s.shape.fields) {
+		f := s.shape.fields[name]
+		if name == "" || name == "-" || strings.Contains(name, ",") {
+			fmt.Fprintf(buf, "// Field %q cannot be expressed in a firestore struct tag.\n", name)
+			continue
+		}
+
+		goName := exportedName(name)
+		base := goName
+		for i := 2; goNames[goName]; i++ {
+			goName = base + strconv.Itoa(i)
+		}
+		goNames[goName] = true
+
+		tag := name
+		if f.present < s.shape.maps {
+			tag += ",omitempty"
+		}
+		tag = "firestore:" + strconv.Quote(tag)
+		if strings.Contains(tag, "`") {
+			tag = strconv.Quote(tag)
+		} else {
+			tag = "`" + tag + "`"
+		}
+
+		typ := g.goType(f, s.name+goName, fmt.Sprintf("%s.%s", s.name, goName))
+		fmt.Fprintf(buf, "\t%s %s %s\n", goName, typ, tag)
+	}
+	buf.WriteString("}\n\n")
+}
+
+// goType returns the Go type of a field of the given shape, declaring nested structs named typeName as needed.
+func (g *structGenerator) goType(s *fieldShape, typeName, fieldName string) string {
+	var t string
+	switch {
+	case len(s.tags) == 1:
+		for tag := range s.tags {
+			t = g.singleType(tag, s, typeName, fieldName)
+		}
+	case len(s.tags) == 2 && s.tags[protoIntTag] && s.tags[protoDoubleTag]:
+		t = "float64"
+	default:
+		return "any"
+	}
+
+	if s.nullable && t != "any" && !strings.HasPrefix(t, "[]") {
+		t = "*" + t
+	}
+	return t
+}
+
+func (g *structGenerator) singleType(tag string, s *fieldShape, typeName, fieldName string) string {
+	switch tag {
+	case protoStringTag:
+		return "string"
+	case protoBoolTag:
+		return "bool"
+	case protoIntTag:
+		return "int64"
+	case protoDoubleTag:
+		return "float64"
+	case protoBytesTag:
+		return "[]byte"
+	case protoTimestampTag:
+		g.imports["time"] = true
+		return "time.Time"
+	case protoGeoPointTag:
+		g.imports["google.golang.org/genproto/googleapis/type/latlng"] = true
+		return "latlng.LatLng"
+	case protoReferenceTag:
+		if g.opts.packageName == "firestruct" {
+			return "Reference"
+		}
+		g.imports["github.com/bennovw/firestruct"] = true
+		return "firestruct.Reference"
+	case protoMapTag:
+		return g.declare(typeName, s, fmt.Sprintf("// %s is the type of %s.", typeName, fieldName))
+	case protoArrayTag:
+		if s.elem == nil || (len(s.elem.tags) == 0 && !s.elem.nullable) {
+			return "[]any"
+		}
+		return "[]" + g.goType(s.elem, typeName+"Item", fieldName+" elements")
+	}
+	return "any"
+}
+
+// sortedImports returns the import paths with standard library packages first.
+func sortedImports(imports map[string]bool) []string {
+	paths := sortedKeys(imports)
+	sort.SliceStable(paths, func(i, j int) bool {
+		return !strings.Contains(paths[i], ".") && strings.Contains(paths[j], ".")
+	})
+	return paths
+}
+
+// commonInitialisms are words written in upper case in Go identifiers.
+var commonInitialisms = map[string]bool{
+	"API": true, "DB": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true,
+	"SQL": true, "TTL": true, "UID": true, "URI": true, "URL": true, "UTC": true, "UUID": true, "XML": true,
+}
+
+// exportedName converts a Firestore field name to an exported Go identifier, e.g. "user_id" and "userId" to "UserID".
+func exportedName(name string) string {
+	var words []string
+	var word []rune
+	var prev rune
+	for _, r := range name {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			if len(word) > 0 {
+				words = append(words, string(word))
+			}
+			word = nil
+		case unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			words = append(words, string(word))
+			word = []rune{r}
+		default:
+			word = append(word, r)
+		}
+		prev = r
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+
+	var sb strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); commonInitialisms[upper] {
+			sb.WriteString(upper)
+			continue
+		}
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		sb.WriteString(string(r))
+	}
+
+	s := sb.String()
+	if s == "" {
+		return "Field"
+	}
+	if first := []rune(s)[0]; !unicode.IsUpper(first) {
+		// Identifiers starting with a digit or caseless letter cannot be exported as is
+		s = "F" + s
+	}
+	return s
+}
@@ -0,0 +1,179 @@
+package firestruct
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bennovw/firestruct/internal/testutil"
+)
+
+func TestGenerateStruct(t *testing.T) {
+	thisFunctionName := "GenerateStruct"
+	tests := []struct {
+		Name     string
+		Samples  []map[string]any
+		Expected string
+	}{
+		{
+			Name: "scalars",
+			Samples: []map[string]any{{
+				"user_id":  map[string]any{"stringValue": "jane"},
+				"age":      map[string]any{"integerValue": "42"},
+				"owner":    map[string]any{"referenceValue": "projects/p/databases/(default)/documents/users/jane"},
+				"photoURL": map[string]any{"stringValue": "https://example.com"},
+			}},
+			Expected: "// User was generated from a sample Firestore document.\n" +
+				"type User struct {\n" +
+				"\tAge      int64                `firestore:\"age\"`\n" +
+				"\tOwner    firestruct.Reference `firestore:\"owner\"`\n" +
+				"\tPhotoURL string               `firestore:\"photoURL\"`\n" +
+				"\tUserID   string               `firestore:\"user_id\"`\n" +
+				"}\n",
+		},
+		{
+			Name: "merged samples",
+			Samples: []map[string]any{
+				{
+					"score": map[string]any{"integerValue": "1"},
+					"nick":  map[string]any{"nullValue": nil},
+					"any":   map[string]any{"booleanValue": true},
+				},
+				{
+					"score": map[string]any{"doubleValue": 1.5},
+					"nick":  map[string]any{"stringValue": "j"},
+					"any":   map[string]any{"stringValue": "x"},
+					"extra": map[string]any{"booleanValue": false},
+				},
+			},
+			Expected: "// User was generated from 2 sample Firestore documents.\n" +
+				"type User struct {\n" +
+				"\tAny   any     `firestore:\"any\"`\n" +
+				"\tExtra bool    `firestore:\"extra,omitempty\"`\n" +
+				"\tNick  *string `firestore:\"nick\"`\n" +
+				"\tScore float64 `firestore:\"score\"`\n" +
+				"}\n",
+		},
+		{
+			Name: "nested maps and arrays",
+			Samples: []map[string]any{{
+				"address": map[string]any{"mapValue": map[string]any{"fields": map[string]any{
+					"city": map[string]any{"stringValue": "Ghent"},
+				}}},
+				"mixed": map[string]any{"arrayValue": map[string]any{"values": []any{
+					map[string]any{"stringValue": "a"},
+					map[string]any{"integerValue": "1"},
+				}}},
+				"pets": map[string]any{"arrayValue": map[string]any{"values": []any{
+					map[string]any{"mapValue": map[string]any{"fields": map[string]any{"name": map[string]any{"stringValue": "Rex"}}}},
+					map[string]any{"mapValue": map[string]any{"fields": map[string]any{"age": map[string]any{"integerValue": "3"}}}},
+				}}},
+				"empty": map[string]any{"arrayValue": map[string]any{}},
+			}},
+			Expected: "// User was generated from a sample Firestore document.\n" +
+				"type User struct {\n" +
+				"\tAddress UserAddress    `firestore:\"address\"`\n" +
+				"\tEmpty   []any          `firestore:\"empty\"`\n" +
+				"\tMixed   []any          `firestore:\"mixed\"`\n" +
+				"\tPets    []UserPetsItem `firestore:\"pets\"`\n" +
+				"}\n\n" +
+				"// UserAddress is the type of User.Address.\n" +
+				"type UserAddress struct {\n" +
+				"\tCity string `firestore:\"city\"`\n" +
+				"}\n\n" +
+				"// UserPetsItem is the type of User.Pets elements.\n" +
+				"type UserPetsItem struct {\n" +
+				"\tAge  int64  `firestore:\"age,omitempty\"`\n" +
+				"\tName string `firestore:\"name,omitempty\"`\n" +
+				"}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := GenerateStruct("User", test.Samples)
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+			if string(result) != test.Expected {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%s\n%s", thisFunctionName, test.Name, result, test.Expected)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		Name       string
+		StructName string
+		Samples    []map[string]any
+	}{
+		{Name: "invalid struct name", StructName: "my struct", Samples: []map[string]any{{}}},
+		{Name: "no samples", StructName: "User"},
+		{Name: "invalid value", StructName: "User", Samples: []map[string]any{{"x": "not wrapped"}}},
+	}
+	for _, test := range errorTests {
+		if _, err := GenerateStruct(test.StructName, test.Samples); err == nil {
+			t.Errorf("%v() test \"%v\" expected an error", thisFunctionName, test.Name)
+		}
+	}
+}
+
+// TestGenerateStructTags verifies that every generated struct tag is accepted by parseTag and maps to the sampled field name.
+func TestGenerateStructTags(t *testing.T) {
+	thisFunctionName := "GenerateStruct"
+	sample := map[string]any{
+		"with space": map[string]any{"stringValue": "a"},
+		"quote\"d":   map[string]any{"stringValue": "a"},
+		"back`tick":  map[string]any{"stringValue": "a"},
+		"comma,name": map[string]any{"stringValue": "a"},
+		"9lives":     map[string]any{"integerValue": "9"},
+	}
+	samples := []map[string]any{sample}
+	for i, fields := range testutil.TestFirebaseDocFields {
+		if i == 10 {
+			// a field named mapValue holding an unwrapped map is not a valid Firestore value
+			continue
+		}
+		samples = append(samples, testutil.WrapBareMaps(fields))
+	}
+
+	src, err := GenerateStruct("Doc", samples, WithPackageName("models"))
+	if err != nil {
+		t.Fatalf("%v() returned error: %v", thisFunctionName, err)
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "doc.go", src, 0)
+	if err != nil {
+		t.Fatalf("%v() returned invalid Go source: %v\n%s", thisFunctionName, err, src)
+	}
+
+	names := map[string]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		field, ok := n.(*ast.Field)
+		if !ok || field.Tag == nil {
+			return true
+		}
+		tag, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			t.Errorf("%v() generated invalid tag literal %s", thisFunctionName, field.Tag.Value)
+			return true
+		}
+		name, keep, _, err := parseTag(reflect.StructTag(tag))
+		if err != nil || !keep {
+			t.Errorf("%v() generated tag %s rejected by parseTag: %v", thisFunctionName, tag, err)
+		}
+		names[name] = true
+		return true
+	})
+
+	for _, name := range []string{"with space", "quote\"d", "back`tick", "9lives", "nestedMapData", "timeData"} {
+		if !names[name] {
+			t.Errorf("%v() did not generate a field tagged %q:\n%s", thisFunctionName, name, src)
+		}
+	}
+	if names["comma,name"] || !strings.Contains(string(src), `Field "comma,name" cannot be expressed`) {
+		t.Errorf("%v() did not skip field \"comma,name\":\n%s", thisFunctionName, src)
+	}
+}
@@ -0,0 +1,101 @@
+package firestruct
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type inlineTestMetadata struct {
+	Owner   string `firestore:"owner"`
+	Version int64  `firestore:"version"`
+}
+
+type inlineTestAudit struct {
+	UpdatedBy string `firestore:"updatedBy"`
+}
+
+type inlineTestDoc struct {
+	Title string             `firestore:"title"`
+	Meta  inlineTestMetadata `firestore:",inline"`
+	Audit *inlineTestAudit   `firestore:",inline"`
+	Extra map[string]any     `firestore:",remain"`
+}
+
+var inlineTestFields = map[string]any{
+	"title":     map[string]any{"stringValue": "Hello"},
+	"owner":     map[string]any{"stringValue": "jane"},
+	"version":   map[string]any{"integerValue": "3"},
+	"updatedBy": map[string]any{"stringValue": "john"},
+	"Extra":     map[string]any{"stringValue": "not the remain field"},
+	"labels": map[string]any{"mapValue": map[string]any{"fields": map[string]any{
+		"env": map[string]any{"stringValue": "prod"},
+	}}},
+}
+
+func TestDataToInlineAndRemain(t *testing.T) {
+	thisFunctionName := "DataTo"
+	doc := FirestoreDocument{Fields: inlineTestFields}
+	expected := inlineTestDoc{
+		Title: "Hello",
+		Meta:  inlineTestMetadata{Owner: "jane", Version: 3},
+		Audit: &inlineTestAudit{UpdatedBy: "john"},
+		Extra: map[string]any{
+			"Extra":  "not the remain field",
+			"labels": map[string]any{"env": "prod"},
+		},
+	}
+
+	var result inlineTestDoc
+	if err := doc.DataTo(&result); err != nil {
+		t.Errorf("%v() returned error: %v", thisFunctionName, err)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("%v() output does not match expected data:\n%+v\n%+v", thisFunctionName, result, expected)
+	}
+
+	// Deleted fields are removed from the remain map
+	event := FirestoreCloudEvent{Value: FirestoreDocument{Fields: map[string]any{"owner": map[string]any{"stringValue": "john"}}}}
+	event.UpdateMask.FieldPaths = []string{"owner", "labels.env"}
+	if err := event.MergeTo(&result); err != nil {
+		t.Errorf("FirestoreCloudEvent.MergeTo() returned error: %v", err)
+	}
+	if result.Meta.Owner != "john" || !reflect.DeepEqual(result.Extra["labels"], map[string]any{}) {
+		t.Errorf("FirestoreCloudEvent.MergeTo() output does not match expected data: %+v", result)
+	}
+
+	errorTests := []struct {
+		Name   string
+		Target any
+	}{
+		{Name: "remain is not a map", Target: &struct {
+			Rest []string `firestore:",remain"`
+		}{}},
+		{Name: "two remain fields", Target: &struct {
+			A map[string]any `firestore:",remain"`
+			B map[string]any `firestore:",remain"`
+		}{}},
+		{Name: "inline is not a struct", Target: &struct {
+			N int `firestore:",inline"`
+		}{}},
+	}
+	for _, test := range errorTests {
+		if err := doc.DataTo(test.Target); err == nil {
+			t.Errorf("%v() test \"%v\" expected an error", thisFunctionName, test.Name)
+		}
+	}
+}
+
+func TestJSONSchemaForInlineAndRemain(t *testing.T) {
+	thisFunctionName := "JSONSchemaFor"
+	schema, err := JSONSchemaFor(reflect.TypeOf(inlineTestDoc{}))
+	if err != nil {
+		t.Fatalf("%v() returned error: %v", thisFunctionName, err)
+	}
+	result, _ := json.Marshal(schema.Defs["inlineTestDoc"])
+	expected := `{"type":"object","properties":{"owner":{"type":"string"},"title":{"type":"string"},"updatedBy":{"type":"string"},"version":{"type":"integer"}},` +
+		`"required":["title","owner","version","updatedBy"],"additionalProperties":{}}`
+	if string(result) != expected {
+		t.Errorf("%v() output does not match expected data:\n%s\n%s", thisFunctionName, result, expected)
+	}
+}
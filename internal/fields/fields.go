@@ -66,6 +66,7 @@ package fields
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"reflect"
 	"sort"
 	"strings"
@@ -98,6 +99,12 @@ type ValidateFunc func(reflect.Type) error
 // TODO(deklerk): is this description accurate?
 type LeafTypesFunc func(reflect.Type) bool
 
+// An Inliner is implemented by the parsed tag of fields that may be inlined. The sub-fields of a struct field whose
+// parsed tag reports Inline() as true are treated as fields of the outer struct, as if the field were embedded.
+type Inliner interface {
+	Inline() bool
+}
+
 // A Cache records information about the fields of struct types.
 //
 // A Cache is safe for use by multiple goroutines.
@@ -170,9 +177,13 @@ type fieldScan struct {
 // If more than one field with the same name exists at the same level of embedding,
 // but exactly one of them is tagged, then the tagged field is reported and the others
 // are ignored.
+// A struct field whose parsed tag implements Inliner and reports Inline() as true is
+// treated as an anonymous struct field without a tag name, its fields are embedded.
+//
+// An error is returned if t is not a struct type.
 func (c *Cache) Fields(t reflect.Type) (List, error) {
-	if t.Kind() != reflect.Struct {
-		panic("fields: Fields of non-struct type")
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("fields: Fields of non-struct type %v", t)
 	}
 	return c.cachedTypeFields(t)
 }
@@ -333,7 +344,22 @@ func (c *Cache) listFields(t reflect.Type) ([]Field, error) {
 				}
 
 				var ntyp reflect.Type
-				if f.Anonymous {
+				inline := false
+				if inliner, ok := other.(Inliner); ok && inliner.Inline() {
+					// Inlined field of type T or *T, treated as an embedded struct
+					// regardless of its name.
+					ntyp = f.Type
+					if ntyp.Kind() == reflect.Ptr {
+						ntyp = ntyp.Elem()
+					}
+					if ntyp.Kind() != reflect.Struct {
+						return nil, fmt.Errorf("fields: inlined field %s of %s is not a struct", f.Name, t)
+					}
+					if !exported {
+						continue
+					}
+					inline = true
+				} else if f.Anonymous {
 					// Anonymous field of type T or *T.
 					ntyp = f.Type
 					if ntyp.Kind() == reflect.Ptr {
@@ -343,7 +369,7 @@ func (c *Cache) listFields(t reflect.Type) ([]Field, error) {
 
 				// Record fields with a tag name, non-anonymous fields, or
 				// anonymous non-struct fields.
-				if tagName != "" || ntyp == nil || ntyp.Kind() != reflect.Struct {
+				if !inline && (tagName != "" || ntyp == nil || ntyp.Kind() != reflect.Struct) {
 					if !exported {
 						continue
 					}
@@ -482,9 +508,9 @@ func ParseStandardTag(key string, t reflect.StructTag) (name string, keep bool,
 	return parts[0], true, options, nil
 }
 
-// isEmptyValue is taken from the encoding/json package in the
-// standard library.
-func isEmptyValue(v reflect.Value) bool {
+// IsEmptyValue reports whether v is an empty value for the omitempty tag option.
+// It is taken from the encoding/json package in the standard library, and also treats the zero time.Time as empty.
+func IsEmptyValue(v reflect.Value) bool {
 	switch v.Kind() {
 	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
 		return v.Len() == 0
@@ -367,6 +367,49 @@ func TestParsedTag(t *testing.T) {
 	}
 }
 
+type inlineTag bool
+
+func (t inlineTag) Inline() bool { return bool(t) }
+
+func inlineTagParser(t reflect.StructTag) (name string, keep bool, other interface{}, err error) {
+	name, keep, opts, err := ParseStandardTag("json", t)
+	return name, keep, inlineTag(len(opts) == 1 && opts[0] == "inline"), err
+}
+
+func TestInline(t *testing.T) {
+	type Inner struct {
+		A int
+		B int `json:"b"`
+	}
+	type S struct {
+		X     int
+		In    Inner  `json:",inline"`
+		Ptr   *Embed `json:"ignored,inline"`
+		Named Inner  `json:"named"`
+	}
+	got, err := NewCache(inlineTagParser, nil, nil).Fields(reflect.TypeOf(S{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Field{
+		{Name: "X", Type: intType, Index: []int{0}, ParsedTag: inlineTag(false)},
+		{Name: "A", Type: intType, Index: []int{1, 0}, ParsedTag: inlineTag(false)},
+		{Name: "b", NameFromTag: true, Type: intType, Index: []int{1, 1}, ParsedTag: inlineTag(false)},
+		{Name: "Em", Type: intType, Index: []int{2, 0}, ParsedTag: inlineTag(false)},
+		{Name: "named", NameFromTag: true, Type: reflect.TypeOf(Inner{}), Index: []int{3}, ParsedTag: inlineTag(false)},
+	}
+	if msg, ok := compareFields(got, want); !ok {
+		t.Error(msg)
+	}
+
+	type Invalid struct {
+		N int `json:",inline"`
+	}
+	if _, err := NewCache(inlineTagParser, nil, nil).Fields(reflect.TypeOf(Invalid{})); err == nil {
+		t.Error("expected an error for an inlined non-struct field")
+	}
+}
+
 func TestValidateFunc(t *testing.T) {
 	type MyInvalidStruct struct {
 		A string
@@ -551,3 +594,37 @@ func TestTagErrors(t *testing.T) {
 		t.Error("want error, got nil")
 	}
 }
+
+func TestNonStruct(t *testing.T) {
+	c := NewCache(nil, nil, nil)
+	for _, typ := range []reflect.Type{nil, reflect.TypeOf(0), reflect.TypeOf(&struct{}{}), reflect.TypeOf(map[string]int{})} {
+		if _, err := c.Fields(typ); err == nil {
+			t.Errorf("%v: want error, got nil", typ)
+		}
+	}
+}
+
+func TestIsEmptyValue(t *testing.T) {
+	var nilPtr *int
+	for _, test := range []struct {
+		in   interface{}
+		want bool
+	}{
+		{"", true},
+		{"a", false},
+		{0, true},
+		{uint8(1), false},
+		{0.0, true},
+		{false, true},
+		{[]int{}, true},
+		{map[string]int{"a": 1}, false},
+		{nilPtr, true},
+		{time.Time{}, true},
+		{time.Unix(1, 0), false},
+		{struct{ X int }{}, false},
+	} {
+		if got := IsEmptyValue(reflect.ValueOf(test.in)); got != test.want {
+			t.Errorf("%#v: got %t, want %t", test.in, got, test.want)
+		}
+	}
+}
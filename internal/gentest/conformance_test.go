@@ -0,0 +1,159 @@
+package gentest
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bennovw/firestruct"
+)
+
+// reflectTask has the fields of Task but not its generated decoder, so DataTo populates it with reflection.
+type reflectTask Task
+
+type conformanceTest struct {
+	Name    string
+	Initial func() Task
+	Input   map[string]any
+}
+
+var conformanceTests = []conformanceTest{
+	{
+		Name: "all fields",
+		Input: map[string]any{
+			"created":   time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+			"baseTitle": "base",
+			"author":    "jane",
+			"note":      "note",
+			"title":     "task",
+			"done":      true,
+			"status":    "open",
+			"priority":  int64(3),
+			"count":     int64(42),
+			"small":     uint8(7),
+			"ratio":     0.5,
+			"score":     1.25,
+			"due":       "2023-01-02T03:04:05Z",
+			"payload":   "aGVsbG8=",
+			"ref":       "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			"tags":      []any{"a", "b"},
+			"counts":    map[string]any{"a": int64(1), "b": 2.0},
+			"labels":    map[string]any{"open": []any{"x", "y"}},
+			"address":   map[string]any{"street": "Main", "City": "Ghent", "zip": "9000"},
+			"owners":    []any{map[string]any{"name": "jane", "addresses": []any{map[string]any{"street": "Side"}}}},
+			"extra":     map[string]any{"any": []any{int64(1), "two"}},
+			"pair":      []any{int64(1), int64(2)},
+			"matrix":    []any{[]any{1.0, 2.0}, []any{}},
+			"nested":    map[string]any{"home": map[string]any{"city": "Ghent"}, "none": nil},
+			"Untagged":  "untagged",
+		},
+	},
+	{
+		Name:  "case insensitive matches",
+		Input: map[string]any{"TITLE": "task", "Done": true, "BASETITLE": "base", "untagged": "u", "address": map[string]any{"STREET": "Main"}},
+	},
+	{
+		Name:  "exact match wins",
+		Input: map[string]any{"title": "exact", "Title": "folded", "TITLE": "folded"},
+	},
+	{
+		Name:  "metadata and unknown fields are ignored",
+		Input: map[string]any{"ID": "x", "id": "y", "unknown": int64(1), "Skipped": "s", "internal": "i", "Audit": map[string]any{"author": "a"}},
+	},
+	{
+		Name: "nulls",
+		Initial: func() Task {
+			return Task{Title: "kept", Tags: []string{"a"}, Address: &Address{City: "Ghent"}, Extra: "x", Counts: map[string]int{"a": 1}, Payload: []byte("p")}
+		},
+		Input: map[string]any{"title": nil, "tags": nil, "address": nil, "extra": nil, "counts": nil, "payload": nil, "count": nil},
+	},
+	{
+		Name: "existing values",
+		Initial: func() Task {
+			return Task{Tags: []string{"a", "b", "c"}, Counts: map[string]int{"a": 1}, Address: &Address{City: "Ghent"}, Pair: [2]int{1, 2}}
+		},
+		Input: map[string]any{"tags": []any{"x"}, "counts": map[string]any{"b": int64(2)}, "address": map[string]any{"street": "Main"}, "pair": []any{int64(5)}},
+	},
+	{
+		Name: "pointer held by interface",
+		Initial: func() Task {
+			return Task{Extra: &Address{City: "Ghent"}}
+		},
+		Input: map[string]any{"extra": map[string]any{"street": "Main"}},
+	},
+	{Name: "type error", Input: map[string]any{"count": "x"}},
+	{Name: "int overflow", Input: map[string]any{"priority": int64(200)}},
+	{Name: "uint overflow", Input: map[string]any{"small": uint64(300)}},
+	{Name: "float overflow", Input: map[string]any{"ratio": math.MaxFloat64}},
+	{Name: "float overflow rounding to the float32 range", Input: map[string]any{"ratio": math.MaxFloat32 * (1 + 1e-9)}},
+	{Name: "float infinity", Input: map[string]any{"ratio": math.Inf(-1)}},
+	{Name: "largest double", Input: map[string]any{"score": math.MaxFloat64}},
+	{Name: "invalid time", Input: map[string]any{"created": "yesterday"}},
+	{Name: "invalid bytes", Input: map[string]any{"payload": "%%%"}},
+	{Name: "invalid uuid", Input: map[string]any{"ref": "x"}},
+	{Name: "nested type error", Input: map[string]any{"owners": []any{map[string]any{"addresses": []any{int64(1)}}}}},
+	{Name: "nested struct error", Input: map[string]any{"address": map[string]any{"zip": int64(9000)}}},
+	{Name: "array type error", Input: map[string]any{"pair": "x"}},
+}
+
+func TestGeneratedDecoderConformance(t *testing.T) {
+	thisFunctionName := "DataTo"
+	for _, test := range conformanceTests {
+		t.Run(test.Name, func(t *testing.T) {
+			var generated, reflective Task
+			if test.Initial != nil {
+				generated, reflective = test.Initial(), test.Initial()
+			}
+
+			genErr := firestruct.DataTo(&generated, test.Input)
+			refErr := firestruct.DataTo((*reflectTask)(&reflective), test.Input)
+
+			if (genErr == nil) != (refErr == nil) {
+				t.Fatalf("%v() test \"%v\" returned different errors: %v\n%v", thisFunctionName, test.Name, genErr, refErr)
+			}
+			if genErr != nil {
+				if expected := strings.ReplaceAll(refErr.Error(), "reflectTask", "Task"); genErr.Error() != expected {
+					t.Errorf("%v() test \"%v\" returned different errors:\n%v\n%v", thisFunctionName, test.Name, genErr, expected)
+				}
+				return
+			}
+			if !reflect.DeepEqual(generated, reflective) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%+v\n%+v", thisFunctionName, test.Name, generated, reflective)
+			}
+		})
+	}
+}
+
+func TestGeneratedDecoderUsed(t *testing.T) {
+	var task Task
+	if _, ok := any(&task).(firestruct.FirestoreDecoder); !ok {
+		t.Fatal("Task does not implement firestruct.FirestoreDecoder")
+	}
+	if _, ok := any((*reflectTask)(&task)).(firestruct.FirestoreDecoder); ok {
+		t.Fatal("reflectTask implements firestruct.FirestoreDecoder")
+	}
+}
+
+func BenchmarkGeneratedDecoder(b *testing.B) {
+	data := conformanceTests[0].Input
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var task Task
+		if err := firestruct.DataTo(&task, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReflectiveDecoder(b *testing.B) {
+	data := conformanceTests[0].Input
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var task reflectTask
+		if err := firestruct.DataTo(&task, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+// Code generated by firestruct-gen. DO NOT EDIT.
+
+package gentest
+
+import (
+	"fmt"
+
+	"github.com/bennovw/firestruct"
+)
+
+// firestoreFieldsTask holds the Firestore field names of the fields of Task, in field order.
+var firestoreFieldsTask = []string{"ID", "created", "baseTitle", "author", "note", "title", "done", "status", "priority", "count", "small", "ratio", "score", "due", "payload", "ref", "tags", "counts", "labels", "address", "owners", "extra", "pair", "matrix", "nested", "Untagged"}
+
+// DecodeFirestore populates x from unwrapped Firestore fields, it implements firestruct.FirestoreDecoder.
+func (x *Task) DecodeFirestore(fields map[string]any) error {
+	var seen [26]bool
+	var folded []string
+	for k, v := range fields {
+		var i int
+		switch k {
+		case "ID":
+			// populated with document metadata
+			continue
+		case "created":
+			i = 1
+		case "baseTitle":
+			i = 2
+		case "author":
+			i = 3
+		case "note":
+			i = 4
+		case "title":
+			i = 5
+		case "done":
+			i = 6
+		case "status":
+			i = 7
+		case "priority":
+			i = 8
+		case "count":
+			i = 9
+		case "small":
+			i = 10
+		case "ratio":
+			i = 11
+		case "score":
+			i = 12
+		case "due":
+			i = 13
+		case "payload":
+			i = 14
+		case "ref":
+			i = 15
+		case "tags":
+			i = 16
+		case "counts":
+			i = 17
+		case "labels":
+			i = 18
+		case "address":
+			i = 19
+		case "owners":
+			i = 20
+		case "extra":
+			i = 21
+		case "pair":
+			i = 22
+		case "matrix":
+			i = 23
+		case "nested":
+			i = 24
+		case "Untagged":
+			i = 25
+		default:
+			// case insensitive matches are decoded last, an exact match of the same field wins
+			folded = append(folded, k)
+			continue
+		}
+		seen[i] = true
+		if err := x.decodeFirestoreField(i, v); err != nil {
+			return err
+		}
+	}
+	for _, k := range folded {
+		i := firestruct.FoldField(firestoreFieldsTask, k)
+		if i < 0 || seen[i] {
+			continue
+		}
+		seen[i] = true
+		if err := x.decodeFirestoreField(i, fields[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeFirestoreField populates the field of x with index i in firestoreFieldsTask.
+func (x *Task) decodeFirestoreField(i int, v any) error {
+	var err error
+	switch i {
+	case 1:
+		if x.Base == nil {
+			x.Base = new(Base)
+		}
+		err = firestruct.DecodeTime(&x.Base.Created, v)
+	case 2:
+		if x.Base == nil {
+			x.Base = new(Base)
+		}
+		err = firestruct.DecodeString(&x.Base.Title, v)
+	case 3:
+		err = firestruct.DecodeString(&x.Audit.Author, v)
+	case 4:
+		err = firestruct.DecodeString(&x.Audit.Note, v)
+	case 5:
+		err = firestruct.DecodeString(&x.Title, v)
+	case 6:
+		err = firestruct.DecodeBool(&x.Done, v)
+	case 7:
+		err = firestruct.DecodeString(&x.Status, v)
+	case 8:
+		err = firestruct.DecodeInt(&x.Priority, v)
+	case 9:
+		err = firestruct.DecodeInt(&x.Count, v)
+	case 10:
+		err = firestruct.DecodeUint(&x.Small, v)
+	case 11:
+		err = firestruct.DecodeFloat(&x.Ratio, v)
+	case 12:
+		err = firestruct.DecodeFloat(&x.Score, v)
+	case 13:
+		err = firestruct.DecodePtr(&x.Due, v, firestruct.DecodeTime)
+	case 14:
+		err = firestruct.DecodeBytes(&x.Payload, v)
+	case 15:
+		err = firestruct.DecodeUUID(&x.Ref, v)
+	case 16:
+		err = firestruct.DecodeSlice(&x.Tags, v, firestruct.DecodeString[string])
+	case 17:
+		err = firestruct.DecodeMap(&x.Counts, v, firestruct.DecodeInt[int])
+	case 18:
+		err = firestruct.DecodeMap(&x.Labels, v, func(p *[]string, v any) error {
+			return firestruct.DecodeSlice(p, v, firestruct.DecodeString[string])
+		})
+	case 19:
+		err = firestruct.DecodePtr(&x.Address, v, firestruct.DecodeStruct[Address])
+	case 20:
+		err = firestruct.DecodeSlice(&x.Owners, v, firestruct.DecodeStruct[Owner])
+	case 21:
+		err = firestruct.DecodeAny(&x.Extra, v)
+	case 22:
+		err = firestruct.DecodeValue(&x.Pair, v)
+	case 23:
+		err = firestruct.DecodeSlice(&x.Matrix, v, func(p *[]float64, v any) error {
+			return firestruct.DecodeSlice(p, v, firestruct.DecodeFloat[float64])
+		})
+	case 24:
+		err = firestruct.DecodeMap(&x.Nested, v, func(p **Address, v any) error {
+			return firestruct.DecodePtr(p, v, firestruct.DecodeStruct[Address])
+		})
+	case 25:
+		err = firestruct.DecodeString(&x.Untagged, v)
+	}
+	if err != nil {
+		return fmt.Errorf("gentest.Task.%s: %w", firestoreFieldsTask[i], err)
+	}
+	return nil
+}
+
+// firestoreFieldsAddress holds the Firestore field names of the fields of Address, in field order.
+var firestoreFieldsAddress = []string{"street", "City", "zip"}
+
+// DecodeFirestore populates x from unwrapped Firestore fields, it implements firestruct.FirestoreDecoder.
+func (x *Address) DecodeFirestore(fields map[string]any) error {
+	var seen [3]bool
+	var folded []string
+	for k, v := range fields {
+		var i int
+		switch k {
+		case "street":
+			i = 0
+		case "City":
+			i = 1
+		case "zip":
+			i = 2
+		default:
+			// case insensitive matches are decoded last, an exact match of the same field wins
+			folded = append(folded, k)
+			continue
+		}
+		seen[i] = true
+		if err := x.decodeFirestoreField(i, v); err != nil {
+			return err
+		}
+	}
+	for _, k := range folded {
+		i := firestruct.FoldField(firestoreFieldsAddress, k)
+		if i < 0 || seen[i] {
+			continue
+		}
+		seen[i] = true
+		if err := x.decodeFirestoreField(i, fields[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeFirestoreField populates the field of x with index i in firestoreFieldsAddress.
+func (x *Address) decodeFirestoreField(i int, v any) error {
+	var err error
+	switch i {
+	case 0:
+		err = firestruct.DecodeString(&x.Street, v)
+	case 1:
+		err = firestruct.DecodeString(&x.City, v)
+	case 2:
+		err = firestruct.DecodePtr(&x.Zip, v, firestruct.DecodeString[string])
+	}
+	if err != nil {
+		return fmt.Errorf("gentest.Address.%s: %w", firestoreFieldsAddress[i], err)
+	}
+	return nil
+}
+
+// firestoreFieldsOwner holds the Firestore field names of the fields of Owner, in field order.
+var firestoreFieldsOwner = []string{"name", "addresses"}
+
+// DecodeFirestore populates x from unwrapped Firestore fields, it implements firestruct.FirestoreDecoder.
+func (x *Owner) DecodeFirestore(fields map[string]any) error {
+	var seen [2]bool
+	var folded []string
+	for k, v := range fields {
+		var i int
+		switch k {
+		case "name":
+			i = 0
+		case "addresses":
+			i = 1
+		default:
+			// case insensitive matches are decoded last, an exact match of the same field wins
+			folded = append(folded, k)
+			continue
+		}
+		seen[i] = true
+		if err := x.decodeFirestoreField(i, v); err != nil {
+			return err
+		}
+	}
+	for _, k := range folded {
+		i := firestruct.FoldField(firestoreFieldsOwner, k)
+		if i < 0 || seen[i] {
+			continue
+		}
+		seen[i] = true
+		if err := x.decodeFirestoreField(i, fields[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeFirestoreField populates the field of x with index i in firestoreFieldsOwner.
+func (x *Owner) decodeFirestoreField(i int, v any) error {
+	var err error
+	switch i {
+	case 0:
+		err = firestruct.DecodeString(&x.Name, v)
+	case 1:
+		err = firestruct.DecodeSlice(&x.Addresses, v, firestruct.DecodeStruct[Address])
+	}
+	if err != nil {
+		return fmt.Errorf("gentest.Owner.%s: %w", firestoreFieldsOwner[i], err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gentest holds the types of the conformance tests of the decoders generated by firestruct-gen.
+package gentest
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//go:generate go run ../../cmd/firestruct-gen -type Task,Address,Owner
+
+type Status string
+
+type Priority int8
+
+type Base struct {
+	ID      string    `firestore:",docid"`
+	Created time.Time `firestore:"created"`
+	Title   string    `firestore:"baseTitle"`
+}
+
+type Audit struct {
+	Author string `firestore:"author"`
+	Note   string `firestore:"note,omitempty"`
+}
+
+type Address struct {
+	Street string `firestore:"street"`
+	City   string
+	Zip    *string `firestore:"zip"`
+}
+
+type Owner struct {
+	Name      string    `firestore:"name"`
+	Addresses []Address `firestore:"addresses"`
+}
+
+type Task struct {
+	*Base
+	Audit    Audit               `firestore:",inline"`
+	Title    string              `firestore:"title"`
+	Done     bool                `firestore:"done"`
+	Status   Status              `firestore:"status"`
+	Priority Priority            `firestore:"priority"`
+	Count    int                 `firestore:"count"`
+	Small    uint8               `firestore:"small"`
+	Ratio    float32             `firestore:"ratio"`
+	Score    float64             `firestore:"score"`
+	Due      *time.Time          `firestore:"due"`
+	Payload  []byte              `firestore:"payload"`
+	Ref      uuid.UUID           `firestore:"ref"`
+	Tags     []string            `firestore:"tags"`
+	Counts   map[string]int      `firestore:"counts"`
+	Labels   map[Status][]string `firestore:"labels"`
+	Address  *Address            `firestore:"address"`
+	Owners   []Owner             `firestore:"owners"`
+	Extra    any                 `firestore:"extra"`
+	Pair     [2]int              `firestore:"pair"`
+	Matrix   [][]float64         `firestore:"matrix"`
+	Nested   map[string]*Address `firestore:"nested"`
+	Skipped  string              `firestore:"-"`
+	internal string
+	Untagged string
+}
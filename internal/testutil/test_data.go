@@ -796,3 +796,53 @@ var StructResults = []any{
 		},
 	},
 }
+
+// protoTags are the Firestore protojson type descriptor tags
+var protoTags = map[string]bool{
+	"nullValue": true, "booleanValue": true, "integerValue": true, "doubleValue": true, "timestampValue": true, "stringValue": true,
+	"bytesValue": true, "referenceValue": true, "geoPointValue": true, "arrayValue": true, "mapValue": true,
+}
+
+// WrapBareMaps returns a copy of the Firestore protojson encoded fields in which array elements holding the fields of a map
+// without a mapValue descriptor, as found in TestFirebaseDocFields, are wrapped in a mapValue.
+func WrapBareMaps(fields map[string]any) map[string]any {
+	out := make(map[string]any, len(fields))
+	for k, v := range fields {
+		out[k] = wrapBareMapsValue(v)
+	}
+	return out
+}
+
+func wrapBareMapsValue(value any) any {
+	m, ok := value.(map[string]any)
+	if !ok || len(m) != 1 {
+		return value
+	}
+	if mv, ok := m["mapValue"].(map[string]any); ok {
+		fields, _ := mv["fields"].(map[string]any)
+		return map[string]any{"mapValue": map[string]any{"fields": WrapBareMaps(fields)}}
+	}
+	av, ok := m["arrayValue"].(map[string]any)
+	if !ok {
+		return value
+	}
+	values, ok := av["values"].([]any)
+	if !ok {
+		return value
+	}
+	out := make([]any, len(values))
+	for i, x := range values {
+		elem, isMap := x.(map[string]any)
+		if !isMap {
+			out[i] = x
+			continue
+		}
+		for k := range elem {
+			if len(elem) != 1 || !protoTags[k] {
+				x = map[string]any{"mapValue": map[string]any{"fields": elem}}
+			}
+		}
+		out[i] = wrapBareMapsValue(x)
+	}
+	return map[string]any{"arrayValue": map[string]any{"values": out}}
+}
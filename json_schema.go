@@ -0,0 +1,327 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// JSONSchemaDraft is the JSON Schema dialect of the schemas returned by JSONSchemaFor and WrappedJSONSchemaFor.
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// A JSONSchema is a JSON Schema document or subschema, it encodes to JSON with encoding/json.
+// Only the keywords used by JSONSchemaFor and WrappedJSONSchemaFor are supported.
+type JSONSchema struct {
+	Schema string                 `json:"$schema,omitempty"`
+	Ref    string                 `json:"$ref,omitempty"`
+	Defs   map[string]*JSONSchema `json:"$defs,omitempty"`
+
+	Type            string        `json:"type,omitempty"`
+	Format          string        `json:"format,omitempty"`
+	ContentEncoding string        `json:"contentEncoding,omitempty"`
+	Pattern         string        `json:"pattern,omitempty"`
+	Enum            []any         `json:"enum,omitempty"`
+	AnyOf           []*JSONSchema `json:"anyOf,omitempty"`
+	Not             *JSONSchema   `json:"not,omitempty"`
+
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	MinProperties        int                    `json:"minProperties,omitempty"`
+	MaxProperties        int                    `json:"maxProperties,omitempty"`
+
+	Items *JSONSchema `json:"items,omitempty"`
+}
+
+// JSONSchemaFor returns a JSON Schema of the unwrapped documents that DataTo decodes into values of type t,
+// in their plain JSON encoding as produced by MarshalPlainJSON with WithoutTypeMarkers.
+//
+// Field names follow the firestore struct tags and fields without the omitempty or default options are required.
+// time.Time values are date-time strings, latlng.LatLng values are latitude/longitude objects,
+// uuid.UUID values are uuid strings and byte slices are base64 strings.
+// Fields of inlined structs are properties of the parent, and the remain field describes additional properties.
+// Struct types are defined in $defs and referenced by name, so recursive types are supported.
+// Types that DataTo cannot populate from Firestore data, such as unsigned integers, are reported as an error.
+func JSONSchemaFor(t reflect.Type) (*JSONSchema, error) {
+	return newJSONSchemaGenerator(false).document(t)
+}
+
+// WrappedJSONSchemaFor returns a JSON Schema of the Firestore protojson encoded fields that DataTo decodes into values of type t,
+// i.e. the "fields" of a Firestore document. It can be used to validate raw payloads before they are decoded.
+// Nullable Go types, such as pointers, slices and maps, also accept nullValue.
+func WrappedJSONSchemaFor(t reflect.Type) (*JSONSchema, error) {
+	return newJSONSchemaGenerator(true).document(t)
+}
+
+// jsonSchemaGenerator builds the schema of a Go type and the definitions of the struct types it references.
+type jsonSchemaGenerator struct {
+	wrapped bool
+	defs    map[string]*JSONSchema
+	names   map[reflect.Type]string // names of the struct types in defs
+}
+
+func newJSONSchemaGenerator(wrapped bool) *jsonSchemaGenerator {
+	return &jsonSchemaGenerator{wrapped: wrapped, defs: map[string]*JSONSchema{}, names: map[reflect.Type]string{}}
+}
+
+// document returns the root schema for documents decoded into t, which must be a struct or a pointer to a struct.
+func (g *jsonSchemaGenerator) document(t reflect.Type) (*JSONSchema, error) {
+	fn := "JSONSchemaFor"
+	if g.wrapped {
+		fn = "WrappedJSONSchemaFor"
+	}
+	if t == nil {
+		return nil, fmt.Errorf("%s error, nil type", fn)
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || isLeafType(t) {
+		return nil, fmt.Errorf("%s error, expecting a struct got: %s", fn, t)
+	}
+
+	root, err := g.structRef(t)
+	if err != nil {
+		return nil, fmt.Errorf("%s error, %w", fn, err)
+	}
+	root.Schema = JSONSchemaDraft
+	root.Defs = g.defs
+	return root, nil
+}
+
+// structRef returns a reference to the definition of the fields of struct type t, adding it to defs if needed.
+func (g *jsonSchemaGenerator) structRef(t reflect.Type) (*JSONSchema, error) {
+	if name, ok := g.names[t]; ok {
+		return &JSONSchema{Ref: "#/$defs/" + name}, nil
+	}
+
+	base := t.Name()
+	if base == "" {
+		base = "Document"
+	}
+	name := base
+	for i := 2; g.defs[name] != nil; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	g.names[t] = name
+	// Reserve the name before recursing, recursive references resolve to it
+	g.defs[name] = &JSONSchema{}
+
+	s, err := g.fields(t)
+	if err != nil {
+		return nil, err
+	}
+	*g.defs[name] = *s
+	return &JSONSchema{Ref: "#/$defs/" + name}, nil
+}
+
+// fields returns the schema of an object holding the fields of struct type t.
+func (g *jsonSchemaGenerator) fields(t reflect.Type) (*JSONSchema, error) {
+	fields, err := fieldCache.Fields(t)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{}}
+	for _, f := range fields {
+		opts, _ := f.ParsedTag.(tagOptions)
+		if opts.metadata != "" {
+			// Document metadata is not part of the fields
+			continue
+		}
+		if opts.remain {
+			// Fields not matching other fields are collected by the remain map
+			if _, err := remainField(t, fields); err != nil {
+				return nil, err
+			}
+			elem, err := g.value(f.Type.Elem())
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.Name, err)
+			}
+			s.AdditionalProperties = elem
+			continue
+		}
+
+		fs, err := g.value(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		s.Properties[f.Name] = fs
+		if opts, ok := f.ParsedTag.(tagOptions); !ok || (!opts.omitEmpty && !opts.hasDefault) {
+			s.Required = append(s.Required, f.Name)
+		}
+	}
+	return s, nil
+}
+
+// value returns the schema of a single value decoded into type t.
+func (g *jsonSchemaGenerator) value(t reflect.Type) (*JSONSchema, error) {
+	switch t {
+	case typeOfByteSlice:
+		return g.nullable(g.scalar(protoBytesTag, &JSONSchema{Type: "string", ContentEncoding: "base64"})), nil
+	case typeOfGoTime:
+		return g.scalar(protoTimestampTag, &JSONSchema{Type: "string", Format: "date-time"}), nil
+	case typeOfProtoTimestamp:
+		// Encoded as a timestamp by FromStruct, but not decoded by DataTo
+		return nil, fmt.Errorf("unsupported type %s", t)
+	case typeOfLatLng:
+		return g.scalar(protoGeoPointTag, &JSONSchema{
+			Type: "object",
+			Properties: map[string]*JSONSchema{
+				"latitude":  {Type: "number"},
+				"longitude": {Type: "number"},
+			},
+		}), nil
+	case typeOfUUID:
+		return g.scalar(protoStringTag, &JSONSchema{Type: "string", Format: "uuid"}), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return g.scalar(protoBoolTag, &JSONSchema{Type: "boolean"}), nil
+
+	case reflect.String:
+		if g.wrapped {
+			// Strings are decoded from both strings and references
+			return &JSONSchema{AnyOf: []*JSONSchema{
+				g.scalar(protoStringTag, &JSONSchema{Type: "string"}),
+				g.scalar(protoReferenceTag, &JSONSchema{Type: "string"}),
+			}}, nil
+		}
+		return &JSONSchema{Type: "string"}, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if g.wrapped {
+			// protojson encodes 64-bit integers as strings
+			return g.scalar(protoIntTag, &JSONSchema{AnyOf: []*JSONSchema{
+				{Type: "string", Pattern: "^-?[0-9]+$"},
+				{Type: "integer"},
+			}}), nil
+		}
+		return &JSONSchema{Type: "integer"}, nil
+
+	case reflect.Float32, reflect.Float64:
+		if g.wrapped {
+			return g.scalar(protoDoubleTag, &JSONSchema{AnyOf: []*JSONSchema{
+				{Type: "number"},
+				{Enum: []any{"NaN", "Infinity", "-Infinity"}},
+			}}), nil
+		}
+		return &JSONSchema{Type: "number"}, nil
+
+	case reflect.Interface:
+		if g.wrapped {
+			// Any single Firestore value
+			return &JSONSchema{Type: "object", MinProperties: 1, MaxProperties: 1}, nil
+		}
+		return &JSONSchema{}, nil
+
+	case reflect.Ptr:
+		s, err := g.value(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return g.nullable(s), nil
+
+	case reflect.Array, reflect.Slice:
+		items, err := g.value(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		var s *JSONSchema
+		if g.wrapped {
+			s = g.object(protoArrayTag, &JSONSchema{
+				Type:                 "object",
+				Properties:           map[string]*JSONSchema{"values": {Type: "array", Items: items}},
+				AdditionalProperties: &JSONSchema{Not: &JSONSchema{}},
+			})
+		} else {
+			s = &JSONSchema{Type: "array", Items: items}
+		}
+		if t.Kind() == reflect.Slice {
+			return g.nullable(s), nil
+		}
+		return s, nil
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key type %s is not a string", t.Key())
+		}
+		elem, err := g.value(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		fields := &JSONSchema{Type: "object", AdditionalProperties: elem}
+		return g.nullable(g.mapValue(fields)), nil
+
+	case reflect.Struct:
+		if t.Name() == "" {
+			fields, err := g.fields(t)
+			if err != nil {
+				return nil, err
+			}
+			return g.mapValue(fields), nil
+		}
+		ref, err := g.structRef(t)
+		if err != nil {
+			return nil, err
+		}
+		return g.mapValue(ref), nil
+	}
+
+	// Unsigned integers are not decoded from Firestore integers, which are signed
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// scalar returns the schema of a value that is wrapped by tag in the protojson encoding.
+func (g *jsonSchemaGenerator) scalar(tag string, s *JSONSchema) *JSONSchema {
+	if !g.wrapped {
+		return s
+	}
+	return g.object(tag, s)
+}
+
+// mapValue returns the schema of a map with the given schema for its fields.
+func (g *jsonSchemaGenerator) mapValue(fields *JSONSchema) *JSONSchema {
+	if !g.wrapped {
+		return fields
+	}
+	return g.object(protoMapTag, &JSONSchema{
+		Type:                 "object",
+		Properties:           map[string]*JSONSchema{"fields": fields},
+		AdditionalProperties: &JSONSchema{Not: &JSONSchema{}},
+	})
+}
+
+// nullable returns a schema that also accepts null values.
+func (g *jsonSchemaGenerator) nullable(s *JSONSchema) *JSONSchema {
+	null := &JSONSchema{Type: "null"}
+	if g.wrapped {
+		null = g.object(protoNullTag, null)
+	}
+	return &JSONSchema{AnyOf: []*JSONSchema{s, null}}
+}
+
+// object returns the schema of an object with a single property named tag.
+func (g *jsonSchemaGenerator) object(tag string, s *JSONSchema) *JSONSchema {
+	return &JSONSchema{
+		Type:                 "object",
+		Properties:           map[string]*JSONSchema{tag: s},
+		Required:             []string{tag},
+		AdditionalProperties: &JSONSchema{Not: &JSONSchema{}},
+	}
+}
@@ -0,0 +1,212 @@
+package firestruct
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	ts "github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/type/latlng"
+
+	"github.com/bennovw/firestruct/internal/testutil"
+)
+
+type jsonSchemaTestNode struct {
+	Name     string                `firestore:"name"`
+	Children []*jsonSchemaTestNode `firestore:"children,omitempty"`
+}
+
+type jsonSchemaTestDoc struct {
+	Created time.Time          `firestore:"created"`
+	Count   int                `firestore:"count,omitempty"`
+	Tags    map[string]float64 `firestore:"tags,omitempty"`
+	Root    jsonSchemaTestNode `firestore:"root"`
+	Ignored string             `firestore:"-"`
+}
+
+func TestJSONSchemaFor(t *testing.T) {
+	thisFunctionName := "JSONSchemaFor"
+	schema, err := JSONSchemaFor(reflect.TypeOf(&jsonSchemaTestDoc{}))
+	if err != nil {
+		t.Fatalf("%v() returned error: %v", thisFunctionName, err)
+	}
+	result, _ := json.Marshal(schema)
+	expected := `{"$schema":"https://json-schema.org/draft/2020-12/schema","$ref":"#/$defs/jsonSchemaTestDoc","$defs":{` +
+		`"jsonSchemaTestDoc":{"type":"object","properties":{"count":{"type":"integer"},"created":{"type":"string","format":"date-time"},` +
+		`"root":{"$ref":"#/$defs/jsonSchemaTestNode"},"tags":{"anyOf":[{"type":"object","additionalProperties":{"type":"number"}},{"type":"null"}]}},` +
+		`"required":["created","root"]},` +
+		`"jsonSchemaTestNode":{"type":"object","properties":{"children":{"anyOf":[{"type":"array","items":{"anyOf":[{"$ref":"#/$defs/jsonSchemaTestNode"},{"type":"null"}]}},{"type":"null"}]},` +
+		`"name":{"type":"string"}},"required":["name"]}}}`
+	if string(result) != expected {
+		t.Errorf("%v() output does not match expected data:\n%s\n%s", thisFunctionName, result, expected)
+	}
+
+	tagged, err := JSONSchemaFor(reflect.TypeOf(testutil.TestTaggedStruct{}))
+	if err != nil {
+		t.Fatalf("%v() test \"tagged struct\" returned error: %v", thisFunctionName, err)
+	}
+	fields := tagged.Defs["TestTaggedStruct"]
+	checks := map[string]string{
+		"timeData":     `{"type":"string","format":"date-time"}`,
+		"uuidData":     `{"type":"string","format":"uuid"}`,
+		"bytesData":    `{"anyOf":[{"type":"string","contentEncoding":"base64"},{"type":"null"}]}`,
+		"geoPointData": `{"type":"object","properties":{"latitude":{"type":"number"},"longitude":{"type":"number"}}}`,
+		"nilData":      `{}`,
+	}
+	for name, want := range checks {
+		got, _ := json.Marshal(fields.Properties[name])
+		if string(got) != want {
+			t.Errorf("%v() test \"tagged struct\" field %s:\n%s\n%s", thisFunctionName, name, got, want)
+		}
+	}
+
+	errorTests := []struct {
+		Name string
+		Type reflect.Type
+	}{
+		{Name: "nil", Type: nil},
+		{Name: "not a struct", Type: reflect.TypeOf(1)},
+		{Name: "leaf struct", Type: reflect.TypeOf(time.Time{})},
+		{Name: "int map key", Type: reflect.TypeOf(struct{ M map[int]string }{})},
+		{Name: "channel", Type: reflect.TypeOf(struct{ C chan int }{})},
+	}
+	for _, test := range errorTests {
+		_, err := JSONSchemaFor(test.Type)
+		if err == nil {
+			t.Errorf("%v() test \"%v\" expected an error", thisFunctionName, test.Name)
+		} else if !strings.HasPrefix(err.Error(), "JSONSchemaFor error, ") || strings.Count(err.Error(), "error, ") != 1 {
+			t.Errorf("%v() test \"%v\" returned an error without a single prefix: %v", thisFunctionName, test.Name, err)
+		}
+	}
+}
+
+func TestWrappedJSONSchemaFor(t *testing.T) {
+	thisFunctionName := "WrappedJSONSchemaFor"
+	type doc struct {
+		Count int                `firestore:"count"`
+		Node  jsonSchemaTestNode `firestore:"node,omitempty"`
+	}
+	schema, err := WrappedJSONSchemaFor(reflect.TypeOf(doc{}))
+	if err != nil {
+		t.Fatalf("%v() returned error: %v", thisFunctionName, err)
+	}
+
+	closed := `"additionalProperties":{"not":{}}`
+	integer := `{"type":"object","properties":{"integerValue":{"anyOf":[{"type":"string","pattern":"^-?[0-9]+$"},{"type":"integer"}]}},"required":["integerValue"],` + closed + `}`
+	null := `{"type":"object","properties":{"nullValue":{"type":"null"}},"required":["nullValue"],` + closed + `}`
+	str := `{"anyOf":[{"type":"object","properties":{"stringValue":{"type":"string"}},"required":["stringValue"],` + closed + `},` +
+		`{"type":"object","properties":{"referenceValue":{"type":"string"}},"required":["referenceValue"],` + closed + `}]}`
+	mapValue := func(fields string) string {
+		return `{"type":"object","properties":{"mapValue":{"type":"object","properties":{"fields":` + fields + `},` + closed + `}},"required":["mapValue"],` + closed + `}`
+	}
+	node := mapValue(`{"$ref":"#/$defs/jsonSchemaTestNode"}`)
+	children := `{"anyOf":[{"type":"object","properties":{"arrayValue":{"type":"object","properties":{"values":{"type":"array","items":{"anyOf":[` + node + `,` + null + `]}}},` + closed + `}},` +
+		`"required":["arrayValue"],` + closed + `},` + null + `]}`
+
+	expected := `{"$schema":"https://json-schema.org/draft/2020-12/schema","$ref":"#/$defs/doc","$defs":{` +
+		`"doc":{"type":"object","properties":{"count":` + integer + `,"node":` + node + `},"required":["count"]},` +
+		`"jsonSchemaTestNode":{"type":"object","properties":{"children":` + children + `,"name":` + str + `},"required":["name"]}}}`
+	result, _ := json.Marshal(schema)
+	if string(result) != expected {
+		t.Errorf("%v() output does not match expected data:\n%s\n%s", thisFunctionName, result, expected)
+	}
+}
+
+// jsonSchemaKindsDoc has a field of every kind JSONSchemaFor describes.
+type jsonSchemaKindsDoc struct {
+	Bool     bool               `firestore:"bool"`
+	String   string             `firestore:"string"`
+	Int      int                `firestore:"int"`
+	Int8     int8               `firestore:"int8"`
+	Int16    int16              `firestore:"int16"`
+	Int32    int32              `firestore:"int32"`
+	Int64    int64              `firestore:"int64"`
+	Float32  float32            `firestore:"float32"`
+	Float64  float64            `firestore:"float64"`
+	Bytes    []byte             `firestore:"bytes"`
+	Time     time.Time          `firestore:"time"`
+	GeoPoint latlng.LatLng      `firestore:"geoPoint"`
+	UUID     uuid.UUID          `firestore:"uuid"`
+	Any      any                `firestore:"any"`
+	Ptr      *string            `firestore:"ptr"`
+	Array    [2]int             `firestore:"array"`
+	Slice    []string           `firestore:"slice"`
+	Map      map[string]float64 `firestore:"map"`
+	Struct   jsonSchemaTestNode `firestore:"struct"`
+}
+
+// TestJSONSchemaForDecodes checks that documents matching the schemas of every supported kind are decoded by DataTo.
+func TestJSONSchemaForDecodes(t *testing.T) {
+	thisFunctionName := "DataTo"
+	for _, gen := range []func(reflect.Type) (*JSONSchema, error){JSONSchemaFor, WrappedJSONSchemaFor} {
+		if _, err := gen(reflect.TypeOf(jsonSchemaKindsDoc{})); err != nil {
+			t.Fatalf("%v() returned error: %v", thisFunctionName, err)
+		}
+	}
+
+	tests := []struct {
+		Name  string
+		Input string
+	}{
+		{
+			Name: "plain",
+			Input: `{"bool":true,"string":"s","int":1,"int8":1,"int16":1,"int32":1,"int64":1,"float32":0.5,"float64":2,` +
+				`"bytes":"aGVsbG8=","time":"2023-01-02T03:04:05Z","geoPoint":{"latitude":51.2,"longitude":3.2},` +
+				`"uuid":"6ba7b810-9dad-11d1-80b4-00c04fd430c8","any":[1,"a"],"ptr":null,"array":[1,2],"slice":["a"],` +
+				`"map":{"a":1},"struct":{"name":"n"}}`,
+		},
+		{
+			Name: "wrapped",
+			Input: `{"bool":{"booleanValue":true},"string":{"referenceValue":"projects/p/databases/(default)/documents/c/d"},` +
+				`"int":{"integerValue":"1"},"int8":{"integerValue":1},"int16":{"integerValue":"1"},"int32":{"integerValue":"1"},` +
+				`"int64":{"integerValue":"-1"},"float32":{"doubleValue":"NaN"},"float64":{"doubleValue":2},` +
+				`"bytes":{"bytesValue":"aGVsbG8="},"time":{"timestampValue":"2023-01-02T03:04:05Z"},` +
+				`"geoPoint":{"geoPointValue":{"latitude":51.2,"longitude":3.2}},"uuid":{"stringValue":"6ba7b810-9dad-11d1-80b4-00c04fd430c8"},` +
+				`"any":{"stringValue":"a"},"ptr":{"nullValue":null},"array":{"arrayValue":{"values":[{"integerValue":"1"}]}},` +
+				`"slice":{"nullValue":null},"map":{"mapValue":{"fields":{"a":{"doubleValue":"Infinity"}}}},` +
+				`"struct":{"mapValue":{"fields":{"name":{"stringValue":"n"}}}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var fields map[string]any
+			if err := json.Unmarshal([]byte(test.Input), &fields); err != nil {
+				t.Fatal(err)
+			}
+			var result jsonSchemaKindsDoc
+			var err error
+			if test.Name == "wrapped" {
+				err = (&FirestoreDocument{Fields: fields}).DataTo(&result)
+			} else {
+				err = DataTo(&result, fields)
+			}
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		Name string
+		Type reflect.Type
+	}{
+		{Name: "uint", Type: reflect.TypeOf(struct{ U uint }{})},
+		{Name: "uint8", Type: reflect.TypeOf(struct{ U uint8 }{})},
+		{Name: "uint16", Type: reflect.TypeOf(struct{ U uint16 }{})},
+		{Name: "uint32", Type: reflect.TypeOf(struct{ U uint32 }{})},
+		{Name: "uint64", Type: reflect.TypeOf(struct{ U uint64 }{})},
+		{Name: "uintptr", Type: reflect.TypeOf(struct{ U uintptr }{})},
+		{Name: "protobuf timestamp", Type: reflect.TypeOf(struct{ T *ts.Timestamp }{})},
+	}
+	for _, test := range errorTests {
+		for _, gen := range []func(reflect.Type) (*JSONSchema, error){JSONSchemaFor, WrappedJSONSchemaFor} {
+			if _, err := gen(test.Type); err == nil {
+				t.Errorf("JSONSchemaFor() test \"%v\" expected an error", test.Name)
+			}
+		}
+	}
+}
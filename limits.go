@@ -0,0 +1,189 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// MaxDocumentFields is the maximum number of fields in a Firestore document, including the fields of nested maps.
+const MaxDocumentFields = 20000
+
+// Limits bound the resources used to unwrap and decode untrusted payloads. A zero limit is not enforced.
+// Sizes are computed with the storage size rules of Firestore, see StorageSize.
+type Limits struct {
+	// MaxDepth is the maximum depth of fields in maps and arrays. Top level fields have depth 1, and every map or array adds a level.
+	MaxDepth int
+	// MaxFields is the maximum total number of fields, including the fields of nested maps.
+	MaxFields int
+	// MaxArrayLength is the maximum number of values in a single array.
+	MaxArrayLength int
+	// MaxValueSize is the maximum size of a single string, bytes or reference value. The size of bytes is checked before they are decoded.
+	MaxValueSize int
+	// MaxDocumentSize is the maximum total size of the field names and values.
+	MaxDocumentSize int
+}
+
+// FirestoreLimits returns the limits of documents stored in Firestore, payloads exceeding them were not sent by Firestore.
+// Arrays are only bounded by the document size.
+func FirestoreLimits() Limits {
+	return Limits{
+		MaxDepth:        MaxFieldDepth,
+		MaxFields:       MaxDocumentFields,
+		MaxValueSize:    MaxFieldValueSize,
+		MaxDocumentSize: MaxDocumentSize,
+	}
+}
+
+// A LimitError is returned when a payload exceeds one of its Limits.
+type LimitError struct {
+	Limit string    // name of the exceeded limit, e.g. "MaxDepth"
+	Max   int       // value of the exceeded limit
+	Path  FieldPath // path of the field exceeding the limit, elements of arrays share the path of the array
+}
+
+func (e *LimitError) Error() string {
+	if len(e.Path) == 0 {
+		return fmt.Sprintf("WithLimits error, document exceeds limit %s of %d", e.Limit, e.Max)
+	}
+	return fmt.Sprintf("WithLimits error, field %s exceeds limit %s of %d", e.Path, e.Limit, e.Max)
+}
+
+// WithLimits enforces limits while the document is unwrapped and before the target is populated, see Limits.
+// A *LimitError is returned when the document exceeds them.
+func WithLimits(limits Limits) DecodeOption {
+	return func(o *decodeOptions) {
+		o.limits = &limits
+	}
+}
+
+// enterFields enters a map with n fields.
+func (u *unwrapper) enterFields(n int) error {
+	u.depth++
+	if u.limits.MaxDepth > 0 && u.depth > u.limits.MaxDepth {
+		return &LimitError{Limit: "MaxDepth", Max: u.limits.MaxDepth}
+	}
+	u.fields += n
+	if u.limits.MaxFields > 0 && u.fields > u.limits.MaxFields {
+		return &LimitError{Limit: "MaxFields", Max: u.limits.MaxFields}
+	}
+	return nil
+}
+
+// enterArray enters an array with n values.
+func (u *unwrapper) enterArray(n int) error {
+	u.depth++
+	if u.limits.MaxDepth > 0 && u.depth > u.limits.MaxDepth {
+		return &LimitError{Limit: "MaxDepth", Max: u.limits.MaxDepth}
+	}
+	if u.limits.MaxArrayLength > 0 && n > u.limits.MaxArrayLength {
+		return &LimitError{Limit: "MaxArrayLength", Max: u.limits.MaxArrayLength}
+	}
+	return nil
+}
+
+// leave leaves the map or array last entered.
+func (u *unwrapper) leave() {
+	u.depth--
+}
+
+// addSize adds size bytes to the size of the document.
+func (u *unwrapper) addSize(size int) error {
+	u.size += size
+	if u.limits.MaxDocumentSize > 0 && u.size > u.limits.MaxDocumentSize {
+		return &LimitError{Limit: "MaxDocumentSize", Max: u.limits.MaxDocumentSize}
+	}
+	return nil
+}
+
+// addValueSize adds the size of a flat value wrapped by tag to the size of the document.
+func (u *unwrapper) addValueSize(tag string, inner any) error {
+	size := fixedValueSize
+	switch tag {
+	case protoNullTag, protoBoolTag:
+		size = smallValueSize
+	case protoGeoPointTag:
+		size = geoPointSize
+	case protoStringTag:
+		if s, ok := inner.(string); ok {
+			size = stringSize(s)
+		}
+	case protoReferenceTag:
+		if s, ok := inner.(string); ok {
+			size = DocumentNameSize(s)
+		}
+	case protoBytesTag:
+		switch b := inner.(type) {
+		case string:
+			size = base64.StdEncoding.DecodedLen(len(b))
+		case []byte:
+			size = len(b)
+		}
+	}
+
+	if u.limits.MaxValueSize > 0 && size > u.limits.MaxValueSize {
+		return &LimitError{Limit: "MaxValueSize", Max: u.limits.MaxValueSize}
+	}
+	return u.addSize(size)
+}
+
+// checkUnwrapped checks an unwrapped value against the limits before it is decoded.
+func (u *unwrapper) checkUnwrapped(data any) error {
+	switch x := data.(type) {
+	case map[string]any:
+		if err := u.enterFields(len(x)); err != nil {
+			return err
+		}
+		defer u.leave()
+
+		for k, v := range x {
+			err := u.addSize(stringSize(k))
+			if err == nil {
+				err = u.checkUnwrapped(v)
+			}
+			var le *LimitError
+			if errors.As(err, &le) {
+				le.Path = append(FieldPath{k}, le.Path...)
+				return le
+			}
+		}
+		return nil
+
+	case []any:
+		if err := u.enterArray(len(x)); err != nil {
+			return err
+		}
+		defer u.leave()
+
+		for _, v := range x {
+			if err := u.checkUnwrapped(v); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case nil:
+		return u.addValueSize(protoNullTag, nil)
+	case bool:
+		return u.addValueSize(protoBoolTag, x)
+	case string:
+		return u.addValueSize(protoStringTag, x)
+	case []byte:
+		return u.addValueSize(protoBytesTag, x)
+	}
+	return u.addValueSize(protoDoubleTag, data)
+}
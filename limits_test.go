@@ -0,0 +1,161 @@
+package firestruct
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bennovw/firestruct/internal/testutil"
+)
+
+// wrappedNestedMap returns the wrapped fields of a map with fields nested depth levels deep.
+func wrappedNestedMap(depth int) map[string]any {
+	m := map[string]any{"a": map[string]any{"stringValue": "leaf"}}
+	for i := 1; i < depth; i++ {
+		m = map[string]any{"a": map[string]any{"mapValue": map[string]any{"fields": m}}}
+	}
+	return m
+}
+
+func TestUnwrapFieldsWithLimits(t *testing.T) {
+	thisFunctionName := "UnwrapFieldsWithLimits"
+	array := map[string]any{"arrayValue": map[string]any{"values": []any{
+		map[string]any{"integerValue": "1"},
+		map[string]any{"integerValue": "2"},
+		map[string]any{"integerValue": "3"},
+	}}}
+
+	tests := []struct {
+		Name     string
+		Input    map[string]any
+		Limits   Limits
+		Expected *LimitError
+	}{
+		{
+			Name:   "within Firestore limits",
+			Input:  testutil.WrapBareMaps(testutil.TestFirebaseDocFields[12]),
+			Limits: FirestoreLimits(),
+		},
+		{
+			Name:     "depth",
+			Input:    wrappedNestedMap(4),
+			Limits:   Limits{MaxDepth: 3},
+			Expected: &LimitError{Limit: "MaxDepth", Max: 3, Path: FieldPath{"a", "a", "a"}},
+		},
+		{
+			Name:   "depth at limit",
+			Input:  wrappedNestedMap(3),
+			Limits: Limits{MaxDepth: 3},
+		},
+		{
+			Name:     "array depth",
+			Input:    map[string]any{"a": map[string]any{"mapValue": map[string]any{"fields": map[string]any{"b": array}}}},
+			Limits:   Limits{MaxDepth: 2},
+			Expected: &LimitError{Limit: "MaxDepth", Max: 2, Path: FieldPath{"a", "b"}},
+		},
+		{
+			Name:     "fields",
+			Input:    wrappedNestedMap(4),
+			Limits:   Limits{MaxFields: 3},
+			Expected: &LimitError{Limit: "MaxFields", Max: 3, Path: FieldPath{"a", "a", "a"}},
+		},
+		{
+			Name:     "array length",
+			Input:    map[string]any{"list": array},
+			Limits:   Limits{MaxArrayLength: 2},
+			Expected: &LimitError{Limit: "MaxArrayLength", Max: 2, Path: FieldPath{"list"}},
+		},
+		{
+			Name:     "string size",
+			Input:    map[string]any{"s": map[string]any{"stringValue": "12345"}},
+			Limits:   Limits{MaxValueSize: 5},
+			Expected: &LimitError{Limit: "MaxValueSize", Max: 5, Path: FieldPath{"s"}},
+		},
+		{
+			Name:     "bytes size",
+			Input:    map[string]any{"b": map[string]any{"bytesValue": strings.Repeat("A", 1<<20)}},
+			Limits:   Limits{MaxValueSize: 1 << 10},
+			Expected: &LimitError{Limit: "MaxValueSize", Max: 1 << 10, Path: FieldPath{"b"}},
+		},
+		{
+			Name:     "document size",
+			Input:    map[string]any{"list": array},
+			Limits:   Limits{MaxDocumentSize: 20},
+			Expected: &LimitError{Limit: "MaxDocumentSize", Max: 20, Path: FieldPath{"list"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := UnwrapFieldsWithLimits(test.Input, test.Limits)
+
+			var le *LimitError
+			if test.Expected == nil {
+				if err != nil {
+					t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+				}
+				return
+			}
+			if !errors.As(err, &le) || !reflect.DeepEqual(le, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%v\n%v", thisFunctionName, test.Name, err, test.Expected)
+			}
+
+			// DataTo enforces the same limits on the wrapped and the unwrapped document
+			doc := FirestoreDocument{Fields: test.Input}
+			var m map[string]any
+			if err := doc.DataTo(&m, WithLimits(test.Limits)); !errors.As(err, &le) {
+				t.Errorf("%v() test \"%v\" returned error: %v", "FirestoreDocument.DataTo", test.Name, err)
+			}
+			unwrapped, _ := UnwrapFields(test.Input)
+			var st testutil.TestTaggedStruct
+			if err := DataTo(&st, unwrapped, WithLimits(test.Limits)); !errors.As(err, &le) {
+				t.Errorf("%v() test \"%v\" returned error: %v", "DataTo", test.Name, err)
+			}
+		})
+	}
+
+	// Selected fields are checked with the depth of their path
+	doc := FirestoreDocument{Fields: wrappedNestedMap(4)}
+	var m map[string]any
+	err := doc.DataTo(&m, WithFields("a.a"), WithLimits(Limits{MaxDepth: 3}))
+	var le *LimitError
+	if !errors.As(err, &le) || le.Path.String() != "a.a.a" {
+		t.Errorf("%v() test \"%v\" returned error: %v", "FirestoreDocument.DataTo", "WithFields", err)
+	}
+}
+
+// FuzzUnwrapFields checks that no JSON payload causes a panic when it is unwrapped or decoded.
+func FuzzUnwrapFields(f *testing.F) {
+	for _, fields := range testutil.TestFirebaseDocFields {
+		b, err := json.Marshal(fields)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+	f.Add([]byte(`{"a":{"arrayValue":{"values":[{"arrayValue":{"values":[{"nullValue":null}]}}]}}}`))
+	f.Add([]byte(`{"a":{"mapValue":{"fields":{"__type__":{"stringValue":"__vector__"},"value":{"arrayValue":{}}}}}}`))
+	f.Add([]byte(`{"a":{"integerValue":"99999999999999999999"},"b":{"bytesValue":"!!"},"c":{"timestampValue":"x"}}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var fields map[string]any
+		if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+			return
+		}
+
+		_, _ = UnwrapFields(fields)
+		_, _ = UnwrapFirestoreFields(fields)
+		_, _ = UnwrapFieldsWithLimits(fields, FirestoreLimits())
+		_, _ = ParseFields(fields)
+
+		doc := FirestoreDocument{Fields: fields}
+		_, _ = doc.StorageSize()
+		_ = doc.CheckConstraints()
+		var st testutil.TestTaggedStruct
+		_ = doc.DataTo(&st, WithLimits(FirestoreLimits()))
+		var m map[string]any
+		_ = doc.DataTo(&m, WithFields("a", "b.c"), WithLimits(FirestoreLimits()))
+	})
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"reflect"
+)
+
+// MergeTo applies the changes described by the event onto p, a pointer to a struct or map[string]interface{}
+// that holds a previous version of the document, such as an entry of an in-memory cache.
+//
+// If the event has an update mask, only the listed fields are unwrapped and merged, and listed fields that are
+// missing from the current version of the document were deleted and are set to their zero value.
+// Without an update mask, e.g. when a document is created, the whole document is merged onto p.
+// Nested maps and structs are deep merged and slices are replaced, pass WithMerge(SliceAppend) to append instead.
+func (e *FirestoreCloudEvent) MergeTo(p interface{}, opts ...DecodeOption) error {
+	mergeOpts := []DecodeOption{WithMerge(SliceReplace)}
+	if len(e.UpdateMask.FieldPaths) > 0 {
+		mergeOpts = append(mergeOpts, WithFields(e.UpdateMask.FieldPaths...), WithZeroMissingFields())
+	}
+
+	return e.Value.DataTo(p, append(mergeOpts, opts...)...)
+}
+
+// mergeValues deep merges src into dst when both are unwrapped maps, or appends src to dst when both are arrays and slices are appended.
+// ok is false if the values cannot be merged and src should replace dst.
+func mergeValues(dst any, src any, slices SliceMergeMode) (merged any, ok bool) {
+	switch d := dst.(type) {
+	case map[string]any:
+		s, isMap := src.(map[string]any)
+		if !isMap {
+			return nil, false
+		}
+		if d == nil {
+			return s, true
+		}
+		for k, v := range s {
+			if existing, found := d[k]; found {
+				if m, ok := mergeValues(existing, v, slices); ok {
+					d[k] = m
+					continue
+				}
+			}
+			d[k] = v
+		}
+		return d, true
+
+	case []any:
+		s, isArray := src.([]any)
+		if !isArray || slices != SliceAppend {
+			return nil, false
+		}
+		return append(d, s...), true
+	}
+
+	return nil, false
+}
+
+// zeroPath sets the value at fp in v, a struct, map or pointer, to its zero value. Map entries are deleted.
+// Missing fields are ignored.
+func zeroPath(v reflect.Value, fp FieldPath) error {
+	if len(fp) == 0 {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return zeroPath(v.Elem(), fp)
+
+	case reflect.Interface:
+		// Values held by an interface are not addressable, but maps can still be modified in place.
+		if m, ok := v.Interface().(map[string]any); ok {
+			DeletePath(m, fp)
+		}
+		return nil
+
+	case reflect.Struct:
+		fs, err := fieldCache.Fields(v.Type())
+		if err != nil {
+			return err
+		}
+		f := matchField(fs, fp[0])
+		if f == nil {
+			// Fields matching no other field are held by the remain field, if any
+			remain, err := remainField(v.Type(), fs)
+			if remain == nil || err != nil {
+				return err
+			}
+			fv, err := v.FieldByIndexErr(remain.Index)
+			if err != nil {
+				return nil
+			}
+			return zeroPath(fv, fp)
+		}
+		fv, err := v.FieldByIndexErr(f.Index)
+		if err != nil {
+			// the field belongs to a nil embedded struct and is already zero
+			return nil
+		}
+		if len(fp) == 1 {
+			fv.Set(reflect.Zero(fv.Type()))
+			return nil
+		}
+		return zeroPath(fv, fp[1:])
+
+	case reflect.Map:
+		if v.IsNil() || v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		key := reflect.ValueOf(fp[0]).Convert(v.Type().Key())
+		if len(fp) == 1 {
+			v.SetMapIndex(key, reflect.Value{})
+			return nil
+		}
+
+		// Map elements are not addressable, so zero the path in a copy and store it back.
+		el := v.MapIndex(key)
+		if !el.IsValid() {
+			return nil
+		}
+		cp := reflect.New(el.Type()).Elem()
+		cp.Set(el)
+		if err := zeroPath(cp, fp[1:]); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, cp)
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package firestruct
+
+import (
+	"reflect"
+	"testing"
+)
+
+type mergeProfile struct {
+	Name  string `firestore:"name"`
+	Email string `firestore:"email"`
+}
+
+type mergeStruct struct {
+	Profile  mergeProfile            `firestore:"profile"`
+	Labels   map[string]string       `firestore:"labels"`
+	Profiles map[string]mergeProfile `firestore:"profiles"`
+	Extra    any                     `firestore:"extra"`
+	Tags     []string                `firestore:"tags"`
+	Count    int64                   `firestore:"count"`
+}
+
+func newMergeStruct() mergeStruct {
+	return mergeStruct{
+		Profile:  mergeProfile{Name: "John", Email: "john@example.com"},
+		Labels:   map[string]string{"env": "dev", "team": "core"},
+		Profiles: map[string]mergeProfile{"owner": {Name: "John", Email: "john@example.com"}},
+		Extra:    map[string]any{"a": map[string]any{"b": 1, "c": 2}, "d": []any{"x"}},
+		Tags:     []string{"x", "y", "z"},
+		Count:    1,
+	}
+}
+
+var mergeDocFields = map[string]any{
+	"profile": map[string]any{"mapValue": map[string]any{"fields": map[string]any{
+		"name": map[string]any{"stringValue": "Jane"},
+	}}},
+	"labels": map[string]any{"mapValue": map[string]any{"fields": map[string]any{
+		"env": map[string]any{"stringValue": "prod"},
+	}}},
+	"profiles": map[string]any{"mapValue": map[string]any{"fields": map[string]any{
+		"owner": map[string]any{"mapValue": map[string]any{"fields": map[string]any{
+			"name": map[string]any{"stringValue": "Jane"},
+		}}},
+	}}},
+	"extra": map[string]any{"mapValue": map[string]any{"fields": map[string]any{
+		"a": map[string]any{"mapValue": map[string]any{"fields": map[string]any{
+			"c": map[string]any{"integerValue": "3"},
+		}}},
+		"d": map[string]any{"arrayValue": map[string]any{"values": []any{
+			map[string]any{"stringValue": "y"},
+		}}},
+	}}},
+	"tags": map[string]any{"arrayValue": map[string]any{"values": []any{
+		map[string]any{"stringValue": "new"},
+	}}},
+}
+
+func TestDataToWithMerge(t *testing.T) {
+	thisMethodName := "FirestoreDocument.DataTo"
+	doc := FirestoreDocument{Fields: mergeDocFields}
+
+	tests := []struct {
+		Name     string
+		Mode     SliceMergeMode
+		Expected mergeStruct
+	}{
+		{
+			Name: "replace slices",
+			Mode: SliceReplace,
+			Expected: mergeStruct{
+				Profile:  mergeProfile{Name: "Jane", Email: "john@example.com"},
+				Labels:   map[string]string{"env": "prod", "team": "core"},
+				Profiles: map[string]mergeProfile{"owner": {Name: "Jane", Email: "john@example.com"}},
+				Extra:    map[string]any{"a": map[string]any{"b": 1, "c": 3}, "d": []any{"y"}},
+				Tags:     []string{"new"},
+				Count:    1,
+			},
+		},
+		{
+			Name: "append slices",
+			Mode: SliceAppend,
+			Expected: mergeStruct{
+				Profile:  mergeProfile{Name: "Jane", Email: "john@example.com"},
+				Labels:   map[string]string{"env": "prod", "team": "core"},
+				Profiles: map[string]mergeProfile{"owner": {Name: "Jane", Email: "john@example.com"}},
+				Extra:    map[string]any{"a": map[string]any{"b": 1, "c": 3}, "d": []any{"x", "y"}},
+				Tags:     []string{"x", "y", "z", "new"},
+				Count:    1,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result := newMergeStruct()
+			if err := doc.DataTo(&result, WithMerge(test.Mode)); err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisMethodName, test.Name, err)
+			}
+			if !reflect.DeepEqual(result, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data: %+v", thisMethodName, test.Name, result)
+			}
+		})
+	}
+
+	// Without merge mode, map values and interfaces are replaced
+	result := newMergeStruct()
+	if err := doc.DataTo(&result); err != nil {
+		t.Errorf("%v() test \"no merge\" returned error: %v", thisMethodName, err)
+	}
+	if result.Profiles["owner"].Email != "" || !reflect.DeepEqual(result.Extra, map[string]any{"a": map[string]any{"c": 3}, "d": []any{"y"}}) {
+		t.Errorf("%v() test \"no merge\" merged values: %+v", thisMethodName, result)
+	}
+}
+
+func TestFirestoreCloudEventMergeTo(t *testing.T) {
+	thisMethodName := "FirestoreCloudEvent.MergeTo"
+	event := FirestoreCloudEvent{Value: FirestoreDocument{Fields: map[string]any{
+		"profile": map[string]any{"mapValue": map[string]any{"fields": map[string]any{
+			"name": map[string]any{"stringValue": "Jane"},
+		}}},
+		"count": map[string]any{"integerValue": "2"},
+	}}}
+	// profile.email, labels.team and extra.a.b were deleted, tags did not change
+	event.UpdateMask.FieldPaths = []string{"profile.name", "profile.email", "count", "labels.team", "extra.a.b", "profiles.owner"}
+
+	result := newMergeStruct()
+	if err := event.MergeTo(&result); err != nil {
+		t.Errorf("%v() returned error: %v", thisMethodName, err)
+	}
+
+	expected := newMergeStruct()
+	expected.Profile = mergeProfile{Name: "Jane"}
+	expected.Count = 2
+	expected.Labels = map[string]string{"env": "dev"}
+	expected.Profiles = map[string]mergeProfile{}
+	expected.Extra = map[string]any{"a": map[string]any{"c": 2}, "d": []any{"x"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("%v() output does not match expected data: %+v", thisMethodName, result)
+	}
+
+	// Without an update mask the whole document is merged
+	event.UpdateMask.FieldPaths = nil
+	result = newMergeStruct()
+	if err := event.MergeTo(&result); err != nil {
+		t.Errorf("%v() returned error: %v", thisMethodName, err)
+	}
+	if result.Profile.Email != "john@example.com" || result.Profile.Name != "Jane" || result.Count != 2 {
+		t.Errorf("%v() without update mask output does not match expected data: %+v", thisMethodName, result)
+	}
+}
+
+func TestZeroPath(t *testing.T) {
+	m := map[string]any{"a": map[string]any{"b": 1, "c": 2}}
+	if err := zeroPath(reflect.ValueOf(&m), FieldPath{"a", "b"}); err != nil {
+		t.Errorf("zeroPath() returned error: %v", err)
+	}
+	if !reflect.DeepEqual(m, map[string]any{"a": map[string]any{"c": 2}}) {
+		t.Errorf("zeroPath() output does not match expected data: %v", m)
+	}
+
+	s := newMergeStruct()
+	var nilStruct *mergeStruct
+	for _, fp := range []FieldPath{{"missing"}, {"profile", "missing"}, {"count", "nested"}} {
+		if err := zeroPath(reflect.ValueOf(&s), fp); err != nil {
+			t.Errorf("zeroPath() on missing path %v returned error: %v", fp, err)
+		}
+		if err := zeroPath(reflect.ValueOf(nilStruct), fp); err != nil {
+			t.Errorf("zeroPath() on nil pointer returned error: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(s, newMergeStruct()) {
+		t.Errorf("zeroPath() modified the struct for missing paths: %+v", s)
+	}
+}
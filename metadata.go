@@ -0,0 +1,94 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Tag options of struct fields populated with document metadata by FirestoreDocument.DataTo
+const (
+	metadataDocID      = "docid"      // the document ID, the last segment of its name
+	metadataDocName    = "docname"    // the full resource name of the document
+	metadataCreateTime = "createTime" // the time the document was created
+	metadataUpdateTime = "updateTime" // the time the document was last updated
+)
+
+// populateMetadata sets the fields of the struct p points to that are tagged with a metadata option.
+// Only the fields of the top level struct, including embedded and inlined structs, are populated.
+// Nothing is done if p is not a pointer to a struct.
+func (d *FirestoreDocument) populateMetadata(p any) error {
+	pv := reflect.ValueOf(p)
+	if d == nil || pv.Kind() != reflect.Ptr || pv.IsNil() || pv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	vs := pv.Elem()
+
+	fs, err := fieldCache.Fields(vs.Type())
+	if err != nil {
+		return err
+	}
+	for i := range fs {
+		f := &fs[i]
+		opts, _ := f.ParsedTag.(tagOptions)
+		if opts.metadata == "" {
+			continue
+		}
+
+		var val any
+		switch opts.metadata {
+		case metadataDocID:
+			val = Reference(d.Name).ID()
+		case metadataDocName:
+			val = d.Name
+		case metadataCreateTime:
+			val = d.CreateTime
+		case metadataUpdateTime:
+			val = d.UpdateTime
+		}
+		if err := setMetadata(fieldByIndex(vs, f.Index), val); err != nil {
+			return fmt.Errorf("%s.%s: %s: %w", vs.Type(), f.Name, opts.metadata, err)
+		}
+	}
+	return nil
+}
+
+// setMetadata sets v to a metadata value. Names and IDs populate string fields, times populate time.Time and *time.Time fields.
+func setMetadata(v reflect.Value, val any) error {
+	switch x := val.(type) {
+	case string:
+		if v.Kind() != reflect.String {
+			return fmt.Errorf("cannot populate %s with a string", v.Type())
+		}
+		v.SetString(x)
+
+	case time.Time:
+		switch v.Type() {
+		case typeOfGoTime:
+			v.Set(reflect.ValueOf(x))
+		case reflect.PtrTo(typeOfGoTime):
+			if x.IsZero() {
+				v.Set(reflect.Zero(v.Type()))
+				return nil
+			}
+			v.Set(reflect.ValueOf(&x))
+		default:
+			return fmt.Errorf("cannot populate %s with a time", v.Type())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package firestruct
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type metadataTestDoc struct {
+	ID      string     `firestore:",docid" validate:"required"`
+	Name    Reference  `firestore:",docname"`
+	Created time.Time  `firestore:",createTime"`
+	Updated *time.Time `firestore:"modified,updateTime"`
+	Title   string     `firestore:"title"`
+}
+
+func TestDataToMetadata(t *testing.T) {
+	thisFunctionName := "DataTo"
+	created, _ := time.Parse(time.RFC3339, "2025-04-14T01:02:03Z")
+	updated := created.Add(time.Hour)
+	doc := FirestoreDocument{
+		Name:       "projects/p/databases/(default)/documents/posts/hello",
+		CreateTime: created,
+		UpdateTime: updated,
+		Fields: map[string]any{
+			"title": map[string]any{"stringValue": "Hello"},
+			// document fields never populate metadata fields
+			"ID":       map[string]any{"stringValue": "spoofed"},
+			"modified": map[string]any{"timestampValue": "2000-01-01T00:00:00Z"},
+		},
+	}
+	expected := metadataTestDoc{
+		ID:      "hello",
+		Name:    "projects/p/databases/(default)/documents/posts/hello",
+		Created: created,
+		Updated: &updated,
+		Title:   "Hello",
+	}
+
+	tests := []struct {
+		Name    string
+		Options []DecodeOption
+	}{
+		{Name: "default"},
+		{Name: "with validation", Options: []DecodeOption{WithValidation()}},
+		{Name: "with fields", Options: []DecodeOption{WithFields("title", "modified")}},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var result metadataTestDoc
+			if err := doc.DataTo(&result, test.Options...); err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%+v\n%+v", thisFunctionName, test.Name, result, expected)
+			}
+		})
+	}
+
+	var wrongType struct {
+		Created string `firestore:",createTime"`
+	}
+	if err := doc.DataTo(&wrongType); err == nil {
+		t.Errorf("%v() test \"wrong type\" expected an error", thisFunctionName)
+	}
+
+	// The package level DataTo has no document metadata
+	var fromMap metadataTestDoc
+	if err := DataTo(&fromMap, map[string]any{"ID": "spoofed", "title": "Hello"}); err != nil || fromMap.ID != "" || fromMap.Title != "Hello" {
+		t.Errorf("%v() test \"map\" returned %+v, error: %v", thisFunctionName, fromMap, err)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// DefaultVersionField is the name of the field holding the schema version of documents, see MigrationRegistry.
+const DefaultVersionField = "schemaVersion"
+
+// A MigrationFunc migrates an unwrapped document from one schema version to the next.
+// It may modify and return the map it receives, or return a new map.
+type MigrationFunc func(map[string]any) (map[string]any, error)
+
+// A MigrationRegistry holds the migration steps of the documents in one or more collections.
+// Documents record their schema version in the VersionField, documents without a version are at version 0.
+// Migrating a document applies the step registered for its version, then the step for the next version,
+// and so on until no step is registered for the version reached, which is then recorded in the VersionField.
+//
+// A MigrationRegistry is safe for use by multiple goroutines.
+type MigrationRegistry struct {
+	// VersionField is the name of the field holding the schema version, DefaultVersionField if empty.
+	VersionField string
+
+	mu    sync.RWMutex
+	steps map[string]map[int64]MigrationFunc // steps by collection ID and version they migrate from
+}
+
+// NewMigrationRegistry returns an empty registry using DefaultVersionField.
+func NewMigrationRegistry() *MigrationRegistry {
+	return &MigrationRegistry{VersionField: DefaultVersionField}
+}
+
+// Register registers the step that migrates documents in the collection with ID collection, e.g. "users",
+// from version fromVersion to version fromVersion+1. Register panics if a step is registered twice for the same version.
+func (r *MigrationRegistry) Register(collection string, fromVersion int64, fn MigrationFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.steps == nil {
+		r.steps = map[string]map[int64]MigrationFunc{}
+	}
+	if r.steps[collection] == nil {
+		r.steps[collection] = map[int64]MigrationFunc{}
+	}
+	if _, dup := r.steps[collection][fromVersion]; dup {
+		panic(fmt.Sprintf("Register error, migration of collection %q from version %d registered twice", collection, fromVersion))
+	}
+	r.steps[collection][fromVersion] = fn
+}
+
+// Migrate applies the registered steps to an unwrapped document of the collection with ID collection and returns the migrated document.
+// If any step was applied, the final version is recorded in the VersionField as an int, like unwrapped integers.
+func (r *MigrationRegistry) Migrate(collection string, m map[string]any) (map[string]any, error) {
+	field := r.versionField()
+	version, err := documentVersion(m[field])
+	if err != nil {
+		return nil, fmt.Errorf("migration of collection %q: %s %w", collection, field, err)
+	}
+
+	r.mu.RLock()
+	steps := r.steps[collection]
+	r.mu.RUnlock()
+
+	migrated := false
+	for {
+		r.mu.RLock()
+		fn, ok := steps[version]
+		r.mu.RUnlock()
+		if !ok {
+			break
+		}
+
+		m, err = fn(m)
+		if err != nil {
+			return nil, fmt.Errorf("migration of collection %q from version %d: %w", collection, version, err)
+		}
+		if m == nil {
+			m = map[string]any{}
+		}
+		version++
+		migrated = true
+	}
+
+	if migrated {
+		m[field] = int(version)
+	}
+	return m, nil
+}
+
+func (r *MigrationRegistry) versionField() string {
+	if r.VersionField == "" {
+		return DefaultVersionField
+	}
+	return r.VersionField
+}
+
+// documentVersion converts the unwrapped value of a version field to a version number, a missing version is version 0.
+func documentVersion(v any) (int64, error) {
+	switch x := v.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return int64(x), nil
+	case int64:
+		return x, nil
+	case float64:
+		if x != math.Trunc(x) {
+			return 0, fmt.Errorf("%v is not a whole number", x)
+		}
+		return int64(x), nil
+	}
+	return 0, fmt.Errorf("has unsupported type %T", v)
+}
+
+// WithMigrations migrates the unwrapped document with the steps registered in reg before populating the target.
+// The collection of a FirestoreDocument is derived from its name, use WithCollection to set it explicitly.
+// The package level DataTo requires WithCollection, and its map may be modified by the migration steps.
+// Fields selected by WithFields are selected from the migrated document.
+func WithMigrations(reg *MigrationRegistry) DecodeOption {
+	return func(o *decodeOptions) {
+		o.migrations = reg
+	}
+}
+
+// WithCollection sets the ID of the collection of the decoded document, e.g. "users", used to select migrations.
+func WithCollection(id string) DecodeOption {
+	return func(o *decodeOptions) {
+		o.collection = id
+	}
+}
@@ -0,0 +1,151 @@
+package firestruct
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type migrationsTestUser struct {
+	Version  int64  `firestore:"schemaVersion"`
+	FullName string `firestore:"fullName"`
+	Email    string `firestore:"email"`
+}
+
+func newMigrationsTestRegistry() *MigrationRegistry {
+	reg := NewMigrationRegistry()
+	// Version 0 stored the name in two fields
+	reg.Register("users", 0, func(m map[string]any) (map[string]any, error) {
+		m["fullName"] = m["first"].(string) + " " + m["last"].(string)
+		delete(m, "first")
+		delete(m, "last")
+		return m, nil
+	})
+	// Version 1 stored the email in a nested map
+	reg.Register("users", 1, func(m map[string]any) (map[string]any, error) {
+		if contact, ok := m["contact"].(map[string]any); ok {
+			m["email"] = contact["email"]
+			delete(m, "contact")
+		}
+		return m, nil
+	})
+	reg.Register("broken", 0, func(m map[string]any) (map[string]any, error) {
+		return nil, errors.New("boom")
+	})
+	return reg
+}
+
+func TestMigrationRegistryMigrate(t *testing.T) {
+	thisMethodName := "MigrationRegistry.Migrate"
+	reg := newMigrationsTestRegistry()
+
+	tests := []struct {
+		Name       string
+		Collection string
+		Input      map[string]any
+		Expected   map[string]any
+	}{
+		{
+			Name:       "from version 0",
+			Collection: "users",
+			Input:      map[string]any{"first": "Jane", "last": "Doe", "contact": map[string]any{"email": "jane@example.com"}},
+			Expected:   map[string]any{"schemaVersion": 2, "fullName": "Jane Doe", "email": "jane@example.com"},
+		},
+		{
+			Name:       "from version 1",
+			Collection: "users",
+			Input:      map[string]any{"schemaVersion": 1, "fullName": "Jane Doe"},
+			Expected:   map[string]any{"schemaVersion": 2, "fullName": "Jane Doe"},
+		},
+		{
+			Name:       "latest version",
+			Collection: "users",
+			Input:      map[string]any{"schemaVersion": float64(2), "fullName": "Jane Doe"},
+			Expected:   map[string]any{"schemaVersion": float64(2), "fullName": "Jane Doe"},
+		},
+		{
+			Name:       "other collection",
+			Collection: "posts",
+			Input:      map[string]any{"title": "Hello"},
+			Expected:   map[string]any{"title": "Hello"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := reg.Migrate(test.Collection, test.Input)
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisMethodName, test.Name, err)
+			}
+			if !reflect.DeepEqual(result, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%v\n%v", thisMethodName, test.Name, result, test.Expected)
+			}
+		})
+	}
+
+	if _, err := reg.Migrate("broken", map[string]any{}); err == nil {
+		t.Errorf("%v() test \"failing step\" expected an error", thisMethodName)
+	}
+	if _, err := reg.Migrate("users", map[string]any{"schemaVersion": "one"}); err == nil {
+		t.Errorf("%v() test \"invalid version\" expected an error", thisMethodName)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("MigrationRegistry.Register() did not panic for a duplicate step")
+		}
+	}()
+	reg.Register("users", 0, nil)
+}
+
+func TestDataToWithMigrations(t *testing.T) {
+	thisFunctionName := "DataTo"
+	reg := newMigrationsTestRegistry()
+	doc := FirestoreDocument{
+		Name: "projects/p/databases/(default)/documents/users/jane",
+		Fields: map[string]any{
+			"schemaVersion": map[string]any{"integerValue": "1"},
+			"fullName":      map[string]any{"stringValue": "Jane Doe"},
+			"contact": map[string]any{"mapValue": map[string]any{"fields": map[string]any{
+				"email": map[string]any{"stringValue": "jane@example.com"},
+			}}},
+		},
+	}
+	expected := migrationsTestUser{Version: 2, FullName: "Jane Doe", Email: "jane@example.com"}
+
+	var user migrationsTestUser
+	if err := doc.DataTo(&user, WithMigrations(reg)); err != nil {
+		t.Errorf("%v() returned error: %v", thisFunctionName, err)
+	}
+	if user != expected {
+		t.Errorf("%v() output does not match expected data:\n%+v\n%+v", thisFunctionName, user, expected)
+	}
+
+	// Fields are selected from the migrated document
+	var email migrationsTestUser
+	if err := doc.DataTo(&email, WithMigrations(reg), WithFields("email")); err != nil {
+		t.Errorf("%v() test \"with fields\" returned error: %v", thisFunctionName, err)
+	}
+	if email != (migrationsTestUser{Email: "jane@example.com"}) {
+		t.Errorf("%v() test \"with fields\" output does not match expected data: %+v", thisFunctionName, email)
+	}
+
+	var fromMap migrationsTestUser
+	m := map[string]any{"first": "Jane", "last": "Doe"}
+	if err := DataTo(&fromMap, m, WithMigrations(reg)); err == nil {
+		t.Errorf("%v() test \"map without collection\" expected an error", thisFunctionName)
+	}
+	if err := DataTo(&fromMap, m, WithMigrations(reg), WithCollection("users")); err != nil || fromMap.FullName != "Jane Doe" {
+		t.Errorf("%v() test \"map with collection\" returned %+v, error: %v", thisFunctionName, fromMap, err)
+	}
+
+	// The collection derived from the document name is not written to the spare capacity of the options
+	opts := make([]DecodeOption, 1, 2)
+	opts[0] = WithMigrations(reg)
+	if err := doc.DataTo(&user, opts...); err != nil {
+		t.Errorf("%v() test \"options with spare capacity\" returned error: %v", thisFunctionName, err)
+	}
+	if opts[:2][1] != nil {
+		t.Errorf("%v() test \"options with spare capacity\" wrote to the options of the caller", thisFunctionName)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"fmt"
+)
+
+// A DecodeOption configures how DataTo unwraps a Firestore document and populates its target.
+type DecodeOption func(*decodeOptions)
+
+// decodeOptions holds the settings of a single DataTo call.
+type decodeOptions struct {
+	fields      []FieldPath        // only decode these field paths, all fields are decoded when nil
+	zeroMissing bool               // zero selected fields that are missing from the document
+	merge       bool               // deep merge into the existing contents of the target
+	slices      SliceMergeMode     // how slices are merged in merge mode
+	validate    bool               // validate the target after decoding
+	migrations  *MigrationRegistry // migrate the unwrapped document before decoding
+	collection  string             // collection ID of the document, derived from the document name when empty
+	limits      *Limits            // limits enforced while unwrapping and decoding, none when nil
+	concurrency int                // number of goroutines used by DecodeAll, GOMAXPROCS when not positive
+	err         error              // first error encountered while applying options
+}
+
+// newDecodeOptions applies opts to a new set of decode options.
+func newDecodeOptions(opts []DecodeOption) *decodeOptions {
+	o := &decodeOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithFields restricts decoding to the given Firestore field paths, such as "name" or "address.city".
+// Only the selected fields are unwrapped and assigned, all other fields of the target are left untouched.
+// Selected fields that are missing from the document are skipped, and an empty list of paths selects no fields.
+//
+// Paths use the Firestore field path syntax, so the field paths of an update mask can be passed directly
+// to decode only the fields that changed:
+//
+//	err := cloudEvent.DataTo(&existing, firestruct.WithFields(cloudEvent.UpdateMask.FieldPaths...))
+func WithFields(paths ...string) DecodeOption {
+	return func(o *decodeOptions) {
+		for _, path := range paths {
+			fp, err := ParseFieldPath(path)
+			if err != nil {
+				if o.err == nil {
+					o.err = fmt.Errorf("WithFields error: %v", err)
+				}
+				continue
+			}
+			o.fields = append(o.fields, fp)
+		}
+		if o.fields == nil {
+			// an empty allowlist selects no fields rather than all fields
+			o.fields = []FieldPath{}
+		}
+	}
+}
+
+// WithFieldPaths is like WithFields but accepts parsed field paths.
+func WithFieldPaths(paths ...FieldPath) DecodeOption {
+	return func(o *decodeOptions) {
+		o.fields = append(o.fields, paths...)
+		if o.fields == nil {
+			o.fields = []FieldPath{}
+		}
+	}
+}
+
+// WithZeroMissingFields sets the fields selected by WithFields that are missing from the document to their zero value,
+// instead of leaving them untouched. Map entries are deleted. Use this to apply deletions listed in an update mask.
+func WithZeroMissingFields() DecodeOption {
+	return func(o *decodeOptions) {
+		o.zeroMissing = true
+	}
+}
+
+// A SliceMergeMode determines how an incoming array is merged into an existing slice in merge mode.
+type SliceMergeMode int
+
+const (
+	// SliceReplace replaces the existing slice with the incoming array.
+	SliceReplace SliceMergeMode = iota
+	// SliceAppend appends the elements of the incoming array to the existing slice.
+	SliceAppend
+)
+
+// WithMerge enables merge mode, which applies the document onto the existing contents of the target with deep merge semantics:
+// nested structs and maps, including map[string]interface{} values, are merged key by key instead of being replaced,
+// and slices are replaced or appended to according to slices.
+func WithMerge(slices SliceMergeMode) DecodeOption {
+	return func(o *decodeOptions) {
+		o.merge = true
+		o.slices = slices
+	}
+}
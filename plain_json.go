@@ -0,0 +1,363 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// A JSONOption configures the plain JSON encoding of documents by MarshalPlainJSON.
+type JSONOption func(*jsonOptions)
+
+// jsonOptions holds the settings applied by JSONOption functions.
+type jsonOptions struct {
+	metadata    bool   // wrap the fields in an object with the document's metadata
+	indent      string // indent nested values with this string, compact output when empty
+	typeMarkers bool   // mark bytes and references with "$bytes" and "$reference" objects
+}
+
+// WithDocumentMetadata includes the document's name, createTime and updateTime in the output.
+// The document fields are nested in a "fields" object:
+//
+//	{"createTime":"...","fields":{...},"name":"projects/...","updateTime":"..."}
+func WithDocumentMetadata() JSONOption {
+	return func(o *jsonOptions) {
+		o.metadata = true
+	}
+}
+
+// WithJSONIndent indents the output, each nesting level is indented with indent.
+func WithJSONIndent(indent string) JSONOption {
+	return func(o *jsonOptions) {
+		o.indent = indent
+	}
+}
+
+// WithoutTypeMarkers encodes bytes as plain base64 strings and references as plain strings,
+// instead of {"$bytes": "..."} and {"$reference": "..."} objects.
+func WithoutTypeMarkers() JSONOption {
+	return func(o *jsonOptions) {
+		o.typeMarkers = false
+	}
+}
+
+// newJSONOptions applies opts to a new set of JSON options.
+func newJSONOptions(opts []JSONOption) *jsonOptions {
+	o := &jsonOptions{typeMarkers: true}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// MarshalPlainJSON encodes the current version of the Firestore document as plain JSON, see FirestoreDocument.MarshalPlainJSON.
+func (e *FirestoreCloudEvent) MarshalPlainJSON(opts ...JSONOption) ([]byte, error) {
+	return e.Value.MarshalPlainJSON(opts...)
+}
+
+// MarshalPlainJSON encodes the document's fields as plain JSON without Firestore protojson tags.
+// Keys are sorted and Firestore data types are represented as follows:
+//   - Timestamps are RFC 3339 strings with nanosecond precision in UTC, e.g. "2025-04-14T01:02:03.5Z".
+//   - GeoPoints are {"latitude": 51.2, "longitude": 3.2} objects.
+//   - Bytes are {"$bytes": "<base64>"} objects and references are {"$reference": "<document name>"} objects,
+//     use WithoutTypeMarkers to encode them as plain strings.
+//   - Doubles that cannot be represented in JSON are the strings "NaN", "Infinity" and "-Infinity".
+//
+// Use WithDocumentMetadata to include the document's name and timestamps.
+func (d *FirestoreDocument) MarshalPlainJSON(opts ...JSONOption) ([]byte, error) {
+	if d == nil {
+		return nil, errors.New("nil document contents")
+	}
+
+	enc := &plainJSONEncoder{opts: newJSONOptions(opts)}
+	if enc.opts.metadata {
+		if err := enc.writeMetadata(d); err != nil {
+			return nil, err
+		}
+	} else if err := enc.writeWrappedFields(d.Fields); err != nil {
+		return nil, err
+	}
+
+	return enc.bytes()
+}
+
+// MarshalPlainJSON encodes an unwrapped map, such as the output of ToMap, as plain JSON using the same representations as FirestoreDocument.MarshalPlainJSON.
+// References cannot be distinguished from strings once unwrapped and are encoded as strings.
+func MarshalPlainJSON(m map[string]any, opts ...JSONOption) ([]byte, error) {
+	enc := &plainJSONEncoder{opts: newJSONOptions(opts)}
+	if err := enc.writeValue(m); err != nil {
+		return nil, err
+	}
+
+	return enc.bytes()
+}
+
+// plainJSONEncoder writes plain JSON to an internal buffer.
+type plainJSONEncoder struct {
+	buf  bytes.Buffer
+	opts *jsonOptions
+}
+
+// bytes returns the encoded JSON, indented if requested.
+func (enc *plainJSONEncoder) bytes() ([]byte, error) {
+	if enc.opts.indent == "" {
+		return enc.buf.Bytes(), nil
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, enc.buf.Bytes(), "", enc.opts.indent); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// writeMetadata writes the document's metadata and its fields.
+func (enc *plainJSONEncoder) writeMetadata(d *FirestoreDocument) error {
+	enc.buf.WriteByte('{')
+	if !d.CreateTime.IsZero() {
+		enc.buf.WriteString(`"createTime":`)
+		enc.writeString(formatTimestamp(d.CreateTime))
+		enc.buf.WriteByte(',')
+	}
+
+	enc.buf.WriteString(`"fields":`)
+	if err := enc.writeWrappedFields(d.Fields); err != nil {
+		return err
+	}
+
+	if d.Name != "" {
+		enc.buf.WriteString(`,"name":`)
+		enc.writeString(d.Name)
+	}
+	if !d.UpdateTime.IsZero() {
+		enc.buf.WriteString(`,"updateTime":`)
+		enc.writeString(formatTimestamp(d.UpdateTime))
+	}
+	enc.buf.WriteByte('}')
+	return nil
+}
+
+// writeWrappedFields writes a map of Firestore protojson encoded fields as a JSON object.
+func (enc *plainJSONEncoder) writeWrappedFields(fields map[string]any) error {
+	enc.buf.WriteByte('{')
+	for i, k := range sortedKeys(fields) {
+		if i > 0 {
+			enc.buf.WriteByte(',')
+		}
+		enc.writeString(k)
+		enc.buf.WriteByte(':')
+		if err := enc.writeWrappedValue(fields[k], false); err != nil {
+			return fmt.Errorf("field %s: %w", quoteSegment(k), err)
+		}
+	}
+	enc.buf.WriteByte('}')
+	return nil
+}
+
+// writeWrappedValue writes a single Firestore protojson encoded value of a document, elem is set for array elements.
+func (enc *plainJSONEncoder) writeWrappedValue(value any, elem bool) error {
+	tag, inner, err := splitWrapped(value, elem)
+	if err != nil {
+		return err
+	}
+
+	switch tag {
+	case protoMapTag:
+		m, ok := inner.(map[string]any)
+		if !ok {
+			return fmt.Errorf("invalid Firestore map: %v", inner)
+		}
+		fields, _ := m["fields"].(map[string]any)
+		return enc.writeWrappedFields(fields)
+
+	case protoArrayTag:
+		a, ok := inner.(map[string]any)
+		if !ok {
+			return fmt.Errorf("invalid Firestore array: %v", inner)
+		}
+		values, _ := a["values"].([]any)
+		enc.buf.WriteByte('[')
+		for i, v := range values {
+			if i > 0 {
+				enc.buf.WriteByte(',')
+			}
+			if err := enc.writeWrappedValue(v, true); err != nil {
+				return err
+			}
+		}
+		enc.buf.WriteByte(']')
+		return nil
+
+	case protoReferenceTag:
+		ref, ok := inner.(string)
+		if !ok {
+			return fmt.Errorf("invalid Firestore reference: %v", inner)
+		}
+		enc.writeReference(ref)
+		return nil
+	}
+
+	x, err := unwrapFlatValue(value)
+	if err != nil {
+		return err
+	}
+	return enc.writeValue(x)
+}
+
+// writeReference writes a document reference, marked as such if type markers are enabled.
+func (enc *plainJSONEncoder) writeReference(ref string) {
+	if enc.opts.typeMarkers {
+		enc.buf.WriteString(`{"$reference":`)
+		enc.writeString(ref)
+		enc.buf.WriteByte('}')
+		return
+	}
+	enc.writeString(ref)
+}
+
+// writeValue writes an unwrapped Go value.
+func (enc *plainJSONEncoder) writeValue(value any) error {
+	switch x := value.(type) {
+	case nil:
+		enc.buf.WriteString("null")
+	case bool:
+		enc.buf.WriteString(strconv.FormatBool(x))
+	case string:
+		enc.writeString(x)
+	case int:
+		enc.buf.WriteString(strconv.FormatInt(int64(x), 10))
+	case int32:
+		enc.buf.WriteString(strconv.FormatInt(int64(x), 10))
+	case int64:
+		enc.buf.WriteString(strconv.FormatInt(x, 10))
+	case float32:
+		return enc.writeDouble(float64(x))
+	case float64:
+		return enc.writeDouble(x)
+	case time.Time:
+		enc.writeString(formatTimestamp(x))
+	case []byte:
+		encoded := base64.StdEncoding.EncodeToString(x)
+		if enc.opts.typeMarkers {
+			enc.buf.WriteString(`{"$bytes":`)
+			enc.writeString(encoded)
+			enc.buf.WriteByte('}')
+		} else {
+			enc.writeString(encoded)
+		}
+	case latlng.LatLng:
+		enc.writeGeoPoint(x.Latitude, x.Longitude)
+	case *latlng.LatLng:
+		if x == nil {
+			enc.buf.WriteString("null")
+			return nil
+		}
+		enc.writeGeoPoint(x.Latitude, x.Longitude)
+	case map[string]any:
+		enc.buf.WriteByte('{')
+		for i, k := range sortedKeys(x) {
+			if i > 0 {
+				enc.buf.WriteByte(',')
+			}
+			enc.writeString(k)
+			enc.buf.WriteByte(':')
+			if err := enc.writeValue(x[k]); err != nil {
+				return fmt.Errorf("field %s: %w", quoteSegment(k), err)
+			}
+		}
+		enc.buf.WriteByte('}')
+	case []any:
+		enc.buf.WriteByte('[')
+		for i, v := range x {
+			if i > 0 {
+				enc.buf.WriteByte(',')
+			}
+			if err := enc.writeValue(v); err != nil {
+				return err
+			}
+		}
+		enc.buf.WriteByte(']')
+	default:
+		// Fall back on encoding/json for values that are not produced by unwrapping Firestore documents
+		b, err := json.Marshal(x)
+		if err != nil {
+			return err
+		}
+		enc.buf.Write(b)
+	}
+	return nil
+}
+
+// writeDouble writes a float, NaN and infinities are written as strings since JSON cannot represent them.
+func (enc *plainJSONEncoder) writeDouble(f float64) error {
+	switch {
+	case math.IsNaN(f):
+		enc.writeString("NaN")
+	case math.IsInf(f, 1):
+		enc.writeString("Infinity")
+	case math.IsInf(f, -1):
+		enc.writeString("-Infinity")
+	default:
+		b, err := json.Marshal(f)
+		if err != nil {
+			return err
+		}
+		enc.buf.Write(b)
+	}
+	return nil
+}
+
+// writeGeoPoint writes a geographical point as a latitude/longitude object.
+func (enc *plainJSONEncoder) writeGeoPoint(lat, lng float64) {
+	enc.buf.WriteString(`{"latitude":`)
+	_ = enc.writeDouble(lat)
+	enc.buf.WriteString(`,"longitude":`)
+	_ = enc.writeDouble(lng)
+	enc.buf.WriteByte('}')
+}
+
+// writeString writes s as a JSON string without escaping HTML characters.
+func (enc *plainJSONEncoder) writeString(s string) {
+	e := json.NewEncoder(&enc.buf)
+	e.SetEscapeHTML(false)
+	_ = e.Encode(s)
+	// Encode terminates each value with a newline
+	enc.buf.Truncate(enc.buf.Len() - 1)
+}
+
+// formatTimestamp formats t as an RFC 3339 string with nanosecond precision in UTC.
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// sortedKeys returns the keys of m in lexicographical order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,127 @@
+package firestruct
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bennovw/firestruct/internal/testutil"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+var plainJSONDocFields = map[string]any{
+	"string":    map[string]any{"stringValue": "<b>Hello</b>"},
+	"int":       map[string]any{"integerValue": "9007199254740993"},
+	"double":    map[string]any{"doubleValue": 1.5},
+	"nan":       map[string]any{"doubleValue": "NaN"},
+	"bool":      map[string]any{"booleanValue": true},
+	"null":      map[string]any{"nullValue": nil},
+	"time":      map[string]any{"timestampValue": "2025-04-14T03:02:03.123456789+02:00"},
+	"bytes":     map[string]any{"bytesValue": "SGVsbG8gV29ybGQ="},
+	"reference": map[string]any{"referenceValue": "projects/p/databases/(default)/documents/users/jane"},
+	"geo":       map[string]any{"geoPointValue": map[string]any{"latitude": 51.2, "longitude": 3.2}},
+	"map": map[string]any{"mapValue": map[string]any{"fields": map[string]any{
+		"b": map[string]any{"stringValue": "b"},
+		"a": map[string]any{"stringValue": "a"},
+	}}},
+	"emptyMap":   map[string]any{"mapValue": map[string]any{}},
+	"array":      map[string]any{"arrayValue": map[string]any{"values": []any{map[string]any{"integerValue": "1"}, map[string]any{"referenceValue": "x"}}}},
+	"emptyArray": map[string]any{"arrayValue": map[string]any{}},
+}
+
+func TestFirestoreDocumentMarshalPlainJSON(t *testing.T) {
+	thisMethodName := "FirestoreDocument.MarshalPlainJSON"
+	createTime, _ := time.Parse(time.RFC3339, "2025-04-14T01:02:03+02:00")
+	doc := FirestoreDocument{
+		Name:       "projects/p/databases/(default)/documents/users/jane",
+		Fields:     plainJSONDocFields,
+		CreateTime: createTime,
+	}
+
+	fields := `"array":[1,{"$reference":"x"}],"bool":true,"bytes":{"$bytes":"SGVsbG8gV29ybGQ="},"double":1.5,"emptyArray":[],"emptyMap":{},` +
+		`"geo":{"latitude":51.2,"longitude":3.2},"int":9007199254740993,"map":{"a":"a","b":"b"},"nan":"NaN","null":null,` +
+		`"reference":{"$reference":"projects/p/databases/(default)/documents/users/jane"},"string":"<b>Hello</b>","time":"2025-04-14T01:02:03.123456789Z"`
+	plainFields := `"array":[1,"x"],"bool":true,"bytes":"SGVsbG8gV29ybGQ=","double":1.5,"emptyArray":[],"emptyMap":{},` +
+		`"geo":{"latitude":51.2,"longitude":3.2},"int":9007199254740993,"map":{"a":"a","b":"b"},"nan":"NaN","null":null,` +
+		`"reference":"projects/p/databases/(default)/documents/users/jane","string":"<b>Hello</b>","time":"2025-04-14T01:02:03.123456789Z"`
+
+	tests := []struct {
+		Name     string
+		Options  []JSONOption
+		Expected string
+	}{
+		{Name: "default", Expected: "{" + fields + "}"},
+		{Name: "without type markers", Options: []JSONOption{WithoutTypeMarkers()}, Expected: "{" + plainFields + "}"},
+		{
+			Name:     "with metadata",
+			Options:  []JSONOption{WithDocumentMetadata()},
+			Expected: `{"createTime":"2025-04-13T23:02:03Z","fields":{` + fields + `},"name":"projects/p/databases/(default)/documents/users/jane"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := doc.MarshalPlainJSON(test.Options...)
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisMethodName, test.Name, err)
+			}
+			if string(result) != test.Expected {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%s\n%s", thisMethodName, test.Name, result, test.Expected)
+			}
+			if !json.Valid(result) {
+				t.Errorf("%v() test \"%v\" returned invalid JSON", thisMethodName, test.Name)
+			}
+		})
+	}
+
+	indented, err := doc.MarshalPlainJSON(WithJSONIndent("  "))
+	if err != nil {
+		t.Errorf("%v() test \"indent\" returned error: %v", thisMethodName, err)
+	}
+	if !json.Valid(indented) || indented[1] != '\n' {
+		t.Errorf("%v() test \"indent\" output is not indented: %s", thisMethodName, indented)
+	}
+
+	invalid := FirestoreDocument{Fields: map[string]any{"x": "not wrapped"}}
+	if _, err := invalid.MarshalPlainJSON(); err == nil {
+		t.Errorf("%v() test \"invalid\" expected an error", thisMethodName)
+	}
+}
+
+func TestMarshalPlainJSON(t *testing.T) {
+	thisFunctionName := "MarshalPlainJSON"
+	ts, _ := time.Parse(time.RFC3339, "2025-04-14T01:02:03Z")
+	m := map[string]any{
+		"z":     int64(1),
+		"a":     []any{math.Inf(1), float32(0.5)},
+		"geo":   &latlng.LatLng{Latitude: 1, Longitude: 2},
+		"time":  ts,
+		"bytes": []byte("hi"),
+		"other": struct{ X int }{X: 1},
+	}
+	expected := `{"a":["Infinity",0.5],"bytes":{"$bytes":"aGk="},"geo":{"latitude":1,"longitude":2},"other":{"X":1},"time":"2025-04-14T01:02:03Z","z":1}`
+
+	result, err := MarshalPlainJSON(m)
+	if err != nil {
+		t.Errorf("%v() returned error: %v", thisFunctionName, err)
+	}
+	if string(result) != expected {
+		t.Errorf("%v() output does not match expected data:\n%s\n%s", thisFunctionName, result, expected)
+	}
+
+	// The wrapped and unwrapped encodings of the test document only differ in the representation of references
+	event := FirestoreCloudEvent{Value: FirestoreDocument{Fields: testutil.TestFirebaseDocFields[12]}}
+	fromEvent, err := event.MarshalPlainJSON(WithoutTypeMarkers())
+	if err != nil {
+		t.Errorf("FirestoreCloudEvent.MarshalPlainJSON() returned error: %v", err)
+	}
+	unwrapped, _ := event.ToMap()
+	fromMap, err := MarshalPlainJSON(unwrapped, WithoutTypeMarkers())
+	if err != nil {
+		t.Errorf("%v() returned error: %v", thisFunctionName, err)
+	}
+	if string(fromEvent) != string(fromMap) {
+		t.Errorf("%v() output does not match FirestoreCloudEvent.MarshalPlainJSON():\n%s\n%s", thisFunctionName, fromMap, fromEvent)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"errors"
+	"sort"
+)
+
+// projectWrappedFields unwraps only the given field paths of a map of Firestore protojson encoded document fields.
+// The result is a sparse unwrapped map that only contains the selected fields, missing fields are skipped and returned separately.
+// The selected values are unwrapped by u, which enforces its limits across all selected fields.
+func projectWrappedFields(fields map[string]any, paths []FieldPath, u *unwrapper) (map[string]any, []FieldPath, error) {
+	output := make(map[string]any)
+	var missing []FieldPath
+	for _, fp := range prunePaths(paths) {
+		val, err := lookupWrappedPath(fields, fp)
+		if errors.Is(err, ErrFieldNotFound) {
+			missing = append(missing, fp)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		u.depth = len(fp)
+		x, err := u.unwrapValue(val, false)
+		if err != nil {
+			var le *LimitError
+			if errors.As(err, &le) {
+				le.Path = append(fp[:len(fp):len(fp)], le.Path...)
+				return nil, nil, le
+			}
+			return nil, nil, err
+		}
+		if err := SetPath(output, fp, x); err != nil {
+			return nil, nil, err
+		}
+	}
+	return output, missing, nil
+}
+
+// projectMap returns a sparse copy of an unwrapped map that only contains the given field paths, missing fields are skipped and returned separately.
+// Selected values are shared with m, not copied.
+func projectMap(m map[string]any, paths []FieldPath) (map[string]any, []FieldPath, error) {
+	output := make(map[string]any)
+	var missing []FieldPath
+	for _, fp := range prunePaths(paths) {
+		x, ok := GetPath(m, fp)
+		if !ok {
+			missing = append(missing, fp)
+			continue
+		}
+		if err := SetPath(output, fp, x); err != nil {
+			return nil, nil, err
+		}
+	}
+	return output, missing, nil
+}
+
+// prunePaths returns the field paths sorted by length, without the paths that are nested below another path in the list.
+// This guarantees a projection never writes into a map that was already selected as a whole.
+func prunePaths(paths []FieldPath) []FieldPath {
+	sorted := make([]FieldPath, len(paths))
+	copy(sorted, paths)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) < len(sorted[j]) })
+
+	var pruned []FieldPath
+	for _, fp := range sorted {
+		covered := false
+		for _, parent := range pruned {
+			if isPathPrefix(parent, fp) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			pruned = append(pruned, fp)
+		}
+	}
+	return pruned
+}
+
+// isPathPrefix reports whether prefix equals fp or is one of its parents.
+func isPathPrefix(prefix, fp FieldPath) bool {
+	if len(prefix) > len(fp) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != fp[i] {
+			return false
+		}
+	}
+	return true
+}
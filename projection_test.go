@@ -0,0 +1,134 @@
+package firestruct
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bennovw/firestruct/internal/testutil"
+)
+
+type projectionAddress struct {
+	Street string `firestore:"street"`
+	City   string `firestore:"city"`
+}
+
+type projectionStruct struct {
+	Name    string            `firestore:"name"`
+	Age     int64             `firestore:"age"`
+	Address projectionAddress `firestore:"address"`
+}
+
+var projectionDocFields = map[string]any{
+	"name": map[string]any{"stringValue": "Jane"},
+	"age":  map[string]any{"integerValue": "42"},
+	"address": map[string]any{
+		"mapValue": map[string]any{
+			"fields": map[string]any{
+				"street": map[string]any{"stringValue": "Korenmarkt"},
+				"city":   map[string]any{"stringValue": "Ghent"},
+			},
+		},
+	},
+}
+
+func TestDataToWithFields(t *testing.T) {
+	thisMethodName := "FirestoreDocument.DataTo"
+	doc := FirestoreDocument{Fields: projectionDocFields}
+	existing := projectionStruct{
+		Name:    "John",
+		Age:     21,
+		Address: projectionAddress{Street: "Veldstraat", City: "Brussels"},
+	}
+
+	tests := []struct {
+		Name     string
+		Fields   []string
+		Expected projectionStruct
+	}{
+		{
+			Name:     "top level field",
+			Fields:   []string{"name"},
+			Expected: projectionStruct{Name: "Jane", Age: 21, Address: projectionAddress{Street: "Veldstraat", City: "Brussels"}},
+		},
+		{
+			Name:     "nested field",
+			Fields:   []string{"address.city"},
+			Expected: projectionStruct{Name: "John", Age: 21, Address: projectionAddress{Street: "Veldstraat", City: "Ghent"}},
+		},
+		{
+			Name:     "overlapping fields",
+			Fields:   []string{"address.city", "age", "address"},
+			Expected: projectionStruct{Name: "John", Age: 42, Address: projectionAddress{Street: "Korenmarkt", City: "Ghent"}},
+		},
+		{
+			Name:     "missing fields are skipped",
+			Fields:   []string{"missing", "address.missing", "name.missing"},
+			Expected: existing,
+		},
+		{
+			Name:     "no fields",
+			Fields:   []string{},
+			Expected: existing,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result := existing
+			err := doc.DataTo(&result, WithFields(test.Fields...))
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisMethodName, test.Name, err)
+			}
+			if !reflect.DeepEqual(result, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data: %+v", thisMethodName, test.Name, result)
+			}
+
+			// Decoding the unwrapped document with the same options must yield the same result
+			unwrapped, err := doc.ToMap()
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error running ToMap(): %v", thisMethodName, test.Name, err)
+			}
+			result = existing
+			err = DataTo(&result, unwrapped, WithFields(test.Fields...))
+			if err != nil {
+				t.Errorf("DataTo() test \"%v\" returned error: %v", test.Name, err)
+			}
+			if !reflect.DeepEqual(result, test.Expected) {
+				t.Errorf("DataTo() test \"%v\" output does not match expected data: %+v", test.Name, result)
+			}
+		})
+	}
+
+	result := existing
+	if err := doc.DataTo(&result, WithFields("a..b")); err == nil {
+		t.Errorf("%v() expected an error for an invalid field path", thisMethodName)
+	}
+	if err := DataTo(&result, "not a map", WithFields("name")); err == nil {
+		t.Errorf("DataTo() expected an error when selecting fields from a string")
+	}
+}
+
+func TestCloudEventDataToWithUpdateMask(t *testing.T) {
+	thisMethodName := "FirestoreCloudEvent.DataTo"
+	event := FirestoreCloudEvent{Value: FirestoreDocument{Fields: testutil.TestFirebaseDocFields[12]}}
+	event.UpdateMask.FieldPaths = []string{"stringData", "intData"}
+
+	result := testutil.TestTaggedStruct{Bool: false, String: "old", Int: 1}
+	if err := event.DataTo(&result, WithFields(event.UpdateMask.FieldPaths...)); err != nil {
+		t.Errorf("%v() returned error: %v", thisMethodName, err)
+	}
+	if result.String != "Hello World" || result.Int != 987654321 {
+		t.Errorf("%v() did not decode the fields in the update mask: %q, %d", thisMethodName, result.String, result.Int)
+	}
+	if result.Bool || result.NestedMap != nil || !result.Time.IsZero() {
+		t.Errorf("%v() decoded fields that are not in the update mask", thisMethodName)
+	}
+}
+
+func TestPrunePaths(t *testing.T) {
+	paths := []FieldPath{{"a", "b", "c"}, {"x"}, {"a", "b"}, {"a", "bc"}, {"x", "y"}}
+	expected := []FieldPath{{"x"}, {"a", "b"}, {"a", "bc"}}
+	if result := prunePaths(paths); !reflect.DeepEqual(result, expected) {
+		t.Errorf("prunePaths() returned %v", result)
+	}
+}
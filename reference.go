@@ -0,0 +1,50 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"strings"
+)
+
+// A Reference is the resource name of a Firestore document stored in a referenceValue,
+// e.g. "projects/myproject/databases/(default)/documents/users/jane".
+// Struct fields of type Reference are populated from references in the same way as strings,
+// but document the intent of the field and provide helpers to inspect the referenced document.
+type Reference string
+
+// Path returns the path of the referenced document relative to the database root, e.g. "users/jane".
+// The reference is returned unchanged if it is not a full resource name.
+func (r Reference) Path() string {
+	s := string(r)
+	if i := strings.Index(s, "/documents/"); i != -1 {
+		return s[i+len("/documents/"):]
+	}
+	return strings.TrimPrefix(s, "/")
+}
+
+// ID returns the ID of the referenced document, the last segment of its path.
+func (r Reference) ID() string {
+	s := string(r)
+	return s[strings.LastIndexByte(s, '/')+1:]
+}
+
+// CollectionID returns the ID of the collection containing the referenced document.
+func (r Reference) CollectionID() string {
+	segments := strings.Split(r.Path(), "/")
+	if len(segments) < 2 {
+		return ""
+	}
+	return segments[len(segments)-2]
+}
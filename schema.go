@@ -0,0 +1,375 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// A FieldType is a Firestore data type.
+type FieldType string
+
+// Firestore data types, each corresponds to a protojson type descriptor tag, e.g. TypeString to "stringValue".
+const (
+	TypeNull      FieldType = "null"
+	TypeBoolean   FieldType = "boolean"
+	TypeInteger   FieldType = "integer"
+	TypeDouble    FieldType = "double"
+	TypeTimestamp FieldType = "timestamp"
+	TypeString    FieldType = "string"
+	TypeBytes     FieldType = "bytes"
+	TypeReference FieldType = "reference"
+	TypeGeoPoint  FieldType = "geoPoint"
+	TypeArray     FieldType = "array"
+	TypeMap       FieldType = "map"
+
+	// TypeVector is a vector embedding, encoded as a map with a "__type__" field set to "__vector__", see VectorValue.
+	TypeVector FieldType = "vector"
+)
+
+// protoTagTypes maps protojson type descriptor tags to Firestore data types
+var protoTagTypes = map[string]FieldType{
+	protoNullTag:      TypeNull,
+	protoBoolTag:      TypeBoolean,
+	protoIntTag:       TypeInteger,
+	protoDoubleTag:    TypeDouble,
+	protoTimestampTag: TypeTimestamp,
+	protoStringTag:    TypeString,
+	protoBytesTag:     TypeBytes,
+	protoReferenceTag: TypeReference,
+	protoGeoPointTag:  TypeGeoPoint,
+	protoArrayTag:     TypeArray,
+	protoMapTag:       TypeMap,
+}
+
+// MaxSchemaExamples is the maximum number of distinct example values recorded per field by Schema.
+const MaxSchemaExamples = 3
+
+// detectFieldType returns the Firestore data type of a single Firestore protojson encoded value.
+// The fields of maps and the values of arrays are returned as inner, other values are returned unwrapped.
+func detectFieldType(value any) (t FieldType, inner any, err error) {
+	tag, wrapped, err := splitWrapped(value, false)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch tag {
+	case protoMapTag:
+		m, _ := wrapped.(map[string]any)
+		fields, _ := m["fields"].(map[string]any)
+		return TypeMap, fields, nil
+	case protoArrayTag:
+		a, _ := wrapped.(map[string]any)
+		values, _ := a["values"].([]any)
+		return TypeArray, values, nil
+	}
+
+	x, err := unwrapFlatValue(value)
+	if err != nil {
+		return "", nil, err
+	}
+	return protoTagTypes[tag], x, nil
+}
+
+// A Schema is inferred from a corpus of Firestore documents.
+// It records the data types observed at every field path, and can report documents that drift from it.
+// Fields of maps nested in arrays are recorded under the path of the array.
+// The zero value is an empty schema ready to use.
+type Schema struct {
+	// Documents is the number of documents added to the schema.
+	Documents int
+
+	fields map[string]*FieldSchema // fields keyed by the string representation of their path
+	maps   map[string]*int         // number of maps observed per path, shared by the fields of the maps; documents have the empty path
+}
+
+// A FieldSchema describes the values observed at a field path.
+type FieldSchema struct {
+	Path FieldPath
+	// Count is the number of times the field was present.
+	Count int
+	// Types counts the data types of the field's values.
+	Types map[FieldType]int
+	// ElementTypes counts the data types of the elements of arrays stored in the field.
+	ElementTypes map[FieldType]int
+	// Examples holds up to MaxSchemaExamples distinct unwrapped values of the field, or of the elements of its arrays.
+	// Maps and arrays are not recorded as examples.
+	Examples []any
+
+	parents *int // number of maps observed that could have contained the field
+}
+
+// Presence returns the fraction of the maps containing the field's path that contain the field, 1 if it is always present.
+func (f *FieldSchema) Presence() float64 {
+	if f.parents == nil || *f.parents == 0 {
+		return 0
+	}
+	return float64(f.Count) / float64(*f.parents)
+}
+
+// Required reports whether the field was present in every map that could contain it.
+func (f *FieldSchema) Required() bool {
+	return f.parents != nil && f.Count == *f.parents
+}
+
+// Nullable reports whether the field was null in any document.
+func (f *FieldSchema) Nullable() bool {
+	return f.Types[TypeNull] > 0
+}
+
+// InferSchema infers a schema from the Firestore protojson encoded fields of one or more documents.
+func InferSchema(docs ...map[string]any) (*Schema, error) {
+	s := &Schema{}
+	for i, fields := range docs {
+		if err := s.Add(fields); err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+	}
+	return s, nil
+}
+
+// Add adds the Firestore protojson encoded fields of a document to the schema.
+func (s *Schema) Add(fields map[string]any) error {
+	if s.fields == nil {
+		s.fields = map[string]*FieldSchema{}
+		s.maps = map[string]*int{}
+	}
+	s.Documents++
+	return s.addMap(nil, fields)
+}
+
+// Field returns the schema of the field at path fp, or nil if the field was never observed.
+func (s *Schema) Field(fp FieldPath) *FieldSchema {
+	return s.fields[fp.String()]
+}
+
+// Fields returns the schemas of all observed fields, sorted by path.
+func (s *Schema) Fields() []*FieldSchema {
+	fields := make([]*FieldSchema, 0, len(s.fields))
+	for _, k := range sortedKeys(s.fields) {
+		fields = append(fields, s.fields[k])
+	}
+	return fields
+}
+
+func (s *Schema) addMap(path FieldPath, fields map[string]any) error {
+	key := path.String()
+	parents, ok := s.maps[key]
+	if !ok {
+		parents = new(int)
+		s.maps[key] = parents
+	}
+	*parents++
+
+	for name, v := range fields {
+		fp := appendFieldPath(path, name)
+		f, ok := s.fields[fp.String()]
+		if !ok {
+			f = &FieldSchema{Path: fp, Types: map[FieldType]int{}, ElementTypes: map[FieldType]int{}, parents: parents}
+			s.fields[fp.String()] = f
+		}
+		f.Count++
+		if err := s.addValue(f, v, false); err != nil {
+			return fmt.Errorf("field %s: %w", fp, err)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) addValue(f *FieldSchema, value any, elem bool) error {
+	t, inner, err := detectFieldType(value)
+	if err != nil {
+		return err
+	}
+
+	if elem {
+		f.ElementTypes[t]++
+	} else {
+		f.Types[t]++
+	}
+
+	switch t {
+	case TypeMap:
+		return s.addMap(f.Path, inner.(map[string]any))
+	case TypeArray:
+		for _, v := range inner.([]any) {
+			if err := s.addValue(f, v, true); err != nil {
+				return err
+			}
+		}
+	case TypeNull:
+	default:
+		f.addExample(inner)
+	}
+	return nil
+}
+
+func (f *FieldSchema) addExample(x any) {
+	if len(f.Examples) >= MaxSchemaExamples {
+		return
+	}
+	for _, e := range f.Examples {
+		if reflect.DeepEqual(e, x) {
+			return
+		}
+	}
+	f.Examples = append(f.Examples, x)
+}
+
+// A DriftKind describes how a document deviates from a schema.
+type DriftKind string
+
+const (
+	// DriftNewField is reported for fields that are not part of the schema.
+	DriftNewField DriftKind = "newField"
+	// DriftMissingField is reported for fields that are required by the schema but missing from the document.
+	DriftMissingField DriftKind = "missingField"
+	// DriftTypeChange is reported for values of a type never observed for the field, including null values of non-nullable fields.
+	DriftTypeChange DriftKind = "typeChange"
+)
+
+// A Drift is a deviation of a document from a schema.
+type Drift struct {
+	Path FieldPath
+	Kind DriftKind
+	// Expected holds the data types observed for the field in the schema, sorted by name.
+	Expected []FieldType
+	// Actual is the data type of the field in the document, empty for missing fields.
+	Actual FieldType
+	// Element is true if the drift concerns the elements of an array.
+	Element bool
+}
+
+func (d Drift) String() string {
+	switch d.Kind {
+	case DriftNewField:
+		return fmt.Sprintf("%s: new field of type %s", d.Path, d.Actual)
+	case DriftMissingField:
+		return fmt.Sprintf("%s: missing required field", d.Path)
+	}
+
+	expected := make([]string, len(d.Expected))
+	for i, t := range d.Expected {
+		expected[i] = string(t)
+	}
+	what := "type"
+	if d.Element {
+		what = "element type"
+	}
+	return fmt.Sprintf("%s: %s %s, expected %s", d.Path, what, d.Actual, strings.Join(expected, " or "))
+}
+
+// Compare compares the Firestore protojson encoded fields of a document against the schema and returns the drift found, sorted by path.
+// New fields are reported without their nested fields, and fields with an unexpected type are not compared any further.
+func (s *Schema) Compare(fields map[string]any) ([]Drift, error) {
+	c := schemaComparison{schema: s, seen: map[string]bool{}}
+	if err := c.compareMap(nil, fields); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(c.drift, func(i, j int) bool {
+		return c.drift[i].Path.String() < c.drift[j].Path.String()
+	})
+	return c.drift, nil
+}
+
+// schemaComparison collects the drift of a document.
+type schemaComparison struct {
+	schema *Schema
+	drift  []Drift
+	seen   map[string]bool // reported drift, elements of an array drift in the same way only once
+}
+
+func (c *schemaComparison) report(d Drift) {
+	key := fmt.Sprintf("%s %s %s %t", d.Path, d.Kind, d.Actual, d.Element)
+	if c.seen[key] {
+		return
+	}
+	c.seen[key] = true
+	c.drift = append(c.drift, d)
+}
+
+func (c *schemaComparison) compareMap(path FieldPath, fields map[string]any) error {
+	for name, v := range fields {
+		fp := appendFieldPath(path, name)
+		f := c.schema.Field(fp)
+		if f == nil {
+			t, _, err := detectFieldType(v)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", fp, err)
+			}
+			c.report(Drift{Path: fp, Kind: DriftNewField, Actual: t})
+			continue
+		}
+		if err := c.compareValue(f, v, false); err != nil {
+			return fmt.Errorf("field %s: %w", fp, err)
+		}
+	}
+
+	for _, f := range c.schema.fields {
+		if len(f.Path) != len(path)+1 || !isPathPrefix(path, f.Path) || !f.Required() {
+			continue
+		}
+		if _, ok := fields[f.Path[len(path)]]; !ok {
+			c.report(Drift{Path: f.Path, Kind: DriftMissingField})
+		}
+	}
+	return nil
+}
+
+func (c *schemaComparison) compareValue(f *FieldSchema, value any, elem bool) error {
+	t, inner, err := detectFieldType(value)
+	if err != nil {
+		return err
+	}
+
+	types := f.Types
+	if elem {
+		types = f.ElementTypes
+	}
+	if types[t] == 0 {
+		c.report(Drift{Path: f.Path, Kind: DriftTypeChange, Expected: sortedFieldTypes(types), Actual: t, Element: elem})
+		return nil
+	}
+
+	switch t {
+	case TypeMap:
+		return c.compareMap(f.Path, inner.(map[string]any))
+	case TypeArray:
+		for _, v := range inner.([]any) {
+			if err := c.compareValue(f, v, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sortedFieldTypes returns the types in a count map sorted by name.
+func sortedFieldTypes(types map[FieldType]int) []FieldType {
+	sorted := make([]FieldType, 0, len(types))
+	for t := range types {
+		sorted = append(sorted, t)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// appendFieldPath returns a new path with name appended to fp, without modifying fp.
+func appendFieldPath(fp FieldPath, name string) FieldPath {
+	return append(fp[:len(fp):len(fp)], name)
+}
@@ -0,0 +1,130 @@
+package firestruct
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bennovw/firestruct/internal/testutil"
+)
+
+var schemaTestDocs = []map[string]any{
+	{
+		"name":    map[string]any{"stringValue": "Jane"},
+		"age":     map[string]any{"integerValue": "42"},
+		"nick":    map[string]any{"nullValue": nil},
+		"address": map[string]any{"mapValue": map[string]any{"fields": map[string]any{"city": map[string]any{"stringValue": "Ghent"}}}},
+		"tags":    map[string]any{"arrayValue": map[string]any{"values": []any{map[string]any{"stringValue": "a"}, map[string]any{"stringValue": "b"}}}},
+	},
+	{
+		"name":    map[string]any{"stringValue": "John"},
+		"age":     map[string]any{"doubleValue": 41.5},
+		"nick":    map[string]any{"stringValue": "J"},
+		"address": map[string]any{"mapValue": map[string]any{"fields": map[string]any{"city": map[string]any{"stringValue": "Ghent"}, "zip": map[string]any{"stringValue": "9000"}}}},
+	},
+}
+
+func TestInferSchema(t *testing.T) {
+	thisFunctionName := "InferSchema"
+	schema, err := InferSchema(schemaTestDocs...)
+	if err != nil {
+		t.Fatalf("%v() returned error: %v", thisFunctionName, err)
+	}
+
+	tests := []struct {
+		Name         string
+		Path         FieldPath
+		Types        map[FieldType]int
+		ElementTypes map[FieldType]int
+		Presence     float64
+		Nullable     bool
+		Examples     []any
+	}{
+		{Name: "mixed numbers", Path: FieldPath{"age"}, Types: map[FieldType]int{TypeInteger: 1, TypeDouble: 1}, Presence: 1, Examples: []any{42, 41.5}},
+		{Name: "nullable", Path: FieldPath{"nick"}, Types: map[FieldType]int{TypeNull: 1, TypeString: 1}, Presence: 1, Nullable: true, Examples: []any{"J"}},
+		{Name: "duplicate examples", Path: FieldPath{"address", "city"}, Types: map[FieldType]int{TypeString: 2}, Presence: 1, Examples: []any{"Ghent"}},
+		{Name: "optional nested field", Path: FieldPath{"address", "zip"}, Types: map[FieldType]int{TypeString: 1}, Presence: 0.5, Examples: []any{"9000"}},
+		{Name: "array", Path: FieldPath{"tags"}, Types: map[FieldType]int{TypeArray: 1}, ElementTypes: map[FieldType]int{TypeString: 2}, Presence: 0.5, Examples: []any{"a", "b"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			f := schema.Field(test.Path)
+			if f == nil {
+				t.Fatalf("%v() test \"%v\" did not record field %s", thisFunctionName, test.Name, test.Path)
+			}
+			if test.ElementTypes == nil {
+				test.ElementTypes = map[FieldType]int{}
+			}
+			if !reflect.DeepEqual(f.Types, test.Types) || !reflect.DeepEqual(f.ElementTypes, test.ElementTypes) {
+				t.Errorf("%v() test \"%v\" recorded types %v and element types %v", thisFunctionName, test.Name, f.Types, f.ElementTypes)
+			}
+			if f.Presence() != test.Presence || f.Nullable() != test.Nullable {
+				t.Errorf("%v() test \"%v\" recorded presence %v and nullable %v", thisFunctionName, test.Name, f.Presence(), f.Nullable())
+			}
+			if !reflect.DeepEqual(f.Examples, test.Examples) {
+				t.Errorf("%v() test \"%v\" recorded examples %v", thisFunctionName, test.Name, f.Examples)
+			}
+		})
+	}
+
+	if schema.Documents != 2 || len(schema.Fields()) != 7 {
+		t.Errorf("%v() recorded %d documents and %d fields", thisFunctionName, schema.Documents, len(schema.Fields()))
+	}
+
+	if _, err := InferSchema(testutil.WrapBareMaps(testutil.TestFirebaseDocFields[12])); err != nil {
+		t.Errorf("%v() test \"fixtures\" returned error: %v", thisFunctionName, err)
+	}
+	if _, err := InferSchema(map[string]any{"x": map[string]any{"y": "not wrapped"}}); err == nil {
+		t.Errorf("%v() test \"invalid\" expected an error", thisFunctionName)
+	}
+}
+
+func TestSchemaCompare(t *testing.T) {
+	thisMethodName := "Schema.Compare"
+	schema, err := InferSchema(schemaTestDocs...)
+	if err != nil {
+		t.Fatalf("InferSchema() returned error: %v", err)
+	}
+
+	tests := []struct {
+		Name     string
+		Input    map[string]any
+		Expected []string
+	}{
+		{Name: "no drift", Input: schemaTestDocs[0]},
+		{
+			Name: "drift",
+			Input: map[string]any{
+				"name":    map[string]any{"integerValue": "1"},
+				"age":     map[string]any{"nullValue": nil},
+				"email":   map[string]any{"stringValue": "jane@example.com"},
+				"address": map[string]any{"mapValue": map[string]any{"fields": map[string]any{}}},
+				"tags":    map[string]any{"arrayValue": map[string]any{"values": []any{map[string]any{"booleanValue": true}, map[string]any{"booleanValue": false}}}},
+			},
+			Expected: []string{
+				"address.city: missing required field",
+				"age: type null, expected double or integer",
+				"email: new field of type string",
+				"name: type integer, expected string",
+				"nick: missing required field",
+				"tags: element type boolean, expected string",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			drift, err := schema.Compare(test.Input)
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisMethodName, test.Name, err)
+			}
+			var result []string
+			for _, d := range drift {
+				result = append(result, d.String())
+			}
+			if !reflect.DeepEqual(result, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%q\n%q", thisMethodName, test.Name, result, test.Expected)
+			}
+		})
+	}
+}
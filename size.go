@@ -0,0 +1,140 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxDocumentSize is the maximum storage size of a Firestore document in bytes, 1 MiB.
+const MaxDocumentSize = 1 << 20
+
+// Storage sizes of Firestore data types, see https://firebase.google.com/docs/firestore/storage-size
+const (
+	documentOverheadSize = 32 // added to the size of every document
+	documentNameOverhead = 16 // added to the size of every document name
+	fixedValueSize       = 8  // integers, doubles, timestamps and vector dimensions
+	geoPointSize         = 16
+	smallValueSize       = 1 // booleans and nulls
+)
+
+// A DocumentSize is the storage size of a document as computed by Firestore, in bytes.
+type DocumentSize struct {
+	// Total is the size of the document: the size of its name and fields, plus 32 bytes of overhead.
+	Total int
+	// Name is the size of the document name, 0 if the name is not known.
+	Name int
+	// Fields holds the size of every top level field, the size of its name included.
+	Fields map[string]int
+}
+
+// Remaining returns the number of bytes that can be added to the document before it exceeds MaxDocumentSize.
+// It is negative if the document is too large.
+func (s *DocumentSize) Remaining() int {
+	return MaxDocumentSize - s.Total
+}
+
+// StorageSize returns the storage size of the document, according to the storage size rules of Firestore.
+func (d *FirestoreDocument) StorageSize() (*DocumentSize, error) {
+	values, err := d.Values()
+	if err != nil {
+		return nil, err
+	}
+	return newDocumentSize(d.Name, values), nil
+}
+
+// SizeOf returns the storage size of the document v would be stored as, v must be a struct, a map with string keys,
+// or a pointer to either. Fields are named and omitted using the same struct tags as DataTo, see ValueOf.
+// The size of the document name is not included, since it is not known.
+func SizeOf(v any) (*DocumentSize, error) {
+	x, err := ValueOf(v)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := x.(MapValue)
+	if !ok {
+		return nil, fmt.Errorf("cannot compute the document size of %T, expecting a struct or map", v)
+	}
+	return newDocumentSize("", m), nil
+}
+
+// newDocumentSize computes the size of a document with the given name and fields.
+func newDocumentSize(name string, fields MapValue) *DocumentSize {
+	s := &DocumentSize{
+		Name:   DocumentNameSize(name),
+		Fields: make(map[string]int, len(fields)),
+	}
+	s.Total = s.Name + documentOverheadSize
+	for k, v := range fields {
+		size := stringSize(k) + ValueSize(v)
+		s.Fields[k] = size
+		s.Total += size
+	}
+	return s
+}
+
+// DocumentNameSize returns the storage size of a document name, such as "projects/p/databases/(default)/documents/users/jane"
+// or "users/jane": the sum of the sizes of its collection and document IDs, plus 16 bytes. It returns 0 for an empty name.
+func DocumentNameSize(name string) int {
+	path := Reference(name).Path()
+	if path == "" {
+		return 0
+	}
+
+	size := documentNameOverhead
+	for _, id := range strings.Split(path, "/") {
+		size += stringSize(id)
+	}
+	return size
+}
+
+// ValueSize returns the storage size of a single value.
+func ValueSize(v Value) int {
+	switch x := derefValue(v).(type) {
+	case NullValue, BoolValue:
+		return smallValueSize
+	case IntegerValue, DoubleValue, TimestampValue:
+		return fixedValueSize
+	case GeoPointValue:
+		return geoPointSize
+	case StringValue:
+		return stringSize(string(x))
+	case BytesValue:
+		return len(x)
+	case ReferenceValue:
+		return DocumentNameSize(string(x))
+	case VectorValue:
+		return len(x) * fixedValueSize
+	case ArrayValue:
+		size := 0
+		for _, elem := range x {
+			size += ValueSize(elem)
+		}
+		return size
+	case MapValue:
+		size := 0
+		for k, elem := range x {
+			size += stringSize(k) + ValueSize(elem)
+		}
+		return size
+	}
+	return 0
+}
+
+// stringSize returns the storage size of a string, its UTF-8 encoded length plus 1.
+func stringSize(s string) int {
+	return len(s) + 1
+}
@@ -0,0 +1,119 @@
+package firestruct
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// taskDoc is the example document of https://firebase.google.com/docs/firestore/storage-size
+var taskDoc = FirestoreDocument{
+	Name: "projects/p/databases/(default)/documents/users/jane/tasks/my_task_id",
+	Fields: map[string]any{
+		"type":        map[string]any{"stringValue": "Personal"},
+		"done":        map[string]any{"booleanValue": false},
+		"priority":    map[string]any{"integerValue": "1"},
+		"description": map[string]any{"stringValue": "Learn Cloud Firestore"},
+	},
+}
+
+type task struct {
+	ID          string    `firestore:",docid"`
+	Type        string    `firestore:"type"`
+	Done        bool      `firestore:"done"`
+	Priority    int       `firestore:"priority"`
+	Description string    `firestore:"description"`
+	Due         time.Time `firestore:"due,omitempty"`
+}
+
+func TestStorageSize(t *testing.T) {
+	thisFunctionName := "StorageSize"
+	result, err := taskDoc.StorageSize()
+	if err != nil {
+		t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, "task", err)
+	}
+
+	expected := &DocumentSize{
+		Total:  147,
+		Name:   44,
+		Fields: map[string]int{"type": 14, "done": 6, "priority": 17, "description": 34},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("%v() test \"%v\" output does not match expected data:\n%+v\n%+v", thisFunctionName, "task", result, expected)
+	}
+	if result.Remaining() != MaxDocumentSize-147 {
+		t.Errorf("%v() test \"%v\" returned %d remaining bytes", thisFunctionName, "task", result.Remaining())
+	}
+}
+
+func TestSizeOf(t *testing.T) {
+	thisFunctionName := "SizeOf"
+	tests := []struct {
+		Name     string
+		Input    any
+		Expected int
+	}{
+		{
+			Name:     "struct",
+			Input:    &task{ID: "my_task_id", Type: "Personal", Priority: 1, Description: "Learn Cloud Firestore"},
+			Expected: 147 - 44,
+		},
+		{
+			Name:     "map",
+			Input:    map[string]any{"type": "Personal", "done": false, "priority": 1, "description": "Learn Cloud Firestore"},
+			Expected: 147 - 44,
+		},
+		{
+			Name:     "empty",
+			Input:    struct{}{},
+			Expected: 32,
+		},
+		{
+			Name: "nested",
+			Input: map[string]any{
+				"tags":  []string{"a", "bc"},                                              // 5 + 2 + 3
+				"point": map[string]any{"x": 1.5, "at": time.Time{}},                      // 6 + 2 + 8 + 3 + 8
+				"ref":   Reference("projects/p/databases/(default)/documents/users/jane"), // 4 + 16 + 6 + 5
+				"data":  []byte("abc"),                                                    // 5 + 3
+				"none":  nil,                                                              // 5 + 1
+			},
+			Expected: 32 + 10 + 27 + 31 + 8 + 6,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := SizeOf(test.Input)
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+				return
+			}
+			if result.Total != test.Expected || result.Name != 0 {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%+v\n%v", thisFunctionName, test.Name, result, test.Expected)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		Name  string
+		Input any
+	}{
+		{Name: "string", Input: "foo"},
+		{Name: "int keys", Input: map[int]string{1: "a"}},
+		{Name: "channel", Input: map[string]any{"c": make(chan int)}},
+	}
+	for _, test := range errorTests {
+		t.Run(test.Name, func(t *testing.T) {
+			if _, err := SizeOf(test.Input); err == nil {
+				t.Errorf("%v() test \"%v\" expected an error", thisFunctionName, test.Name)
+			}
+		})
+	}
+
+	// A large array approaching the limit
+	large, _ := SizeOf(map[string]any{"log": []string{strings.Repeat("x", MaxDocumentSize)}})
+	if large.Remaining() >= 0 {
+		t.Errorf("%v() test \"%v\" returned %d remaining bytes", thisFunctionName, "large", large.Remaining())
+	}
+}
@@ -15,18 +15,27 @@
 //
 //	Fork of cloud.google.com/go/firestore with the following changes:
 // 	* Removed dependency on the Firestore serverTimestamp
+// 	* Added the default tag option
+// 	* Added the inline and remain tag options
+// 	* Added the docid, docname, createTime and updateTime tag options
 
 package firestruct
 
 import (
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/bennovw/firestruct/internal/fields"
 )
 
 type tagOptions struct {
-	omitEmpty bool // do not marshal value if empty
+	omitEmpty    bool   // do not marshal value if empty
+	inline       bool   // treat the fields of the struct field as fields of the parent
+	remain       bool   // collect the document fields not matched by other fields in this map field
+	metadata     string // populate the field with this document metadata option instead of a document field
+	hasDefault   bool   // set the field to defaultValue when it is missing from a document
+	defaultValue string // value of the default option, it cannot contain commas
 }
 
 // parseTag interprets firestore struct field tags.
@@ -37,12 +46,26 @@ func parseTag(t reflect.StructTag) (name string, keep bool, other interface{}, e
 	}
 	tagOpts := tagOptions{}
 	for _, opt := range opts {
-		switch opt {
-		case "omitempty":
+		switch {
+		case opt == "omitempty":
 			tagOpts.omitEmpty = true
+		case opt == "inline":
+			tagOpts.inline = true
+		case opt == "remain":
+			tagOpts.remain = true
+		case opt == metadataDocID, opt == metadataDocName, opt == metadataCreateTime, opt == metadataUpdateTime:
+			tagOpts.metadata = opt
+		case strings.HasPrefix(opt, "default="):
+			tagOpts.hasDefault = true
+			tagOpts.defaultValue = strings.TrimPrefix(opt, "default=")
 		default:
 			return "", false, nil, fmt.Errorf("unknown tag option: %q", opt)
 		}
 	}
 	return name, keep, tagOpts, nil
 }
+
+// Inline implements fields.Inliner, inlined struct fields are embedded by the field cache.
+func (o tagOptions) Inline() bool {
+	return o.inline
+}
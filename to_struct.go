@@ -56,10 +56,29 @@ var (
 //	var p Person
 //	err := dataToReflectPointer(reflect.ValueOf(p).Elem(), map[string]interface{}{"Name": "John", "Age": 21})
 func dataToReflectPointer(p reflect.Value, data any) error {
-	typeErr := func() error {
-		return fmt.Errorf("cannot use value %T to populate %s ", data, p.Type())
+	return newDecoder(nil).dataToReflectPointer(p, data)
+}
+
+// A decoder populates Go values from unwrapped Firestore data according to a set of decode options.
+type decoder struct {
+	opts *decodeOptions
+}
+
+// newDecoder returns a decoder using o, or the default decode options if o is nil.
+func newDecoder(o *decodeOptions) *decoder {
+	if o == nil {
+		o = &decodeOptions{}
 	}
+	return &decoder{opts: o}
+}
+
+// dataToReflectPointer uses data to set p, see the package level dataToReflectPointer function.
+func (d *decoder) dataToReflectPointer(p reflect.Value, data any) error {
+	return d.decode(decodePlan(p.Type()), p, data)
+}
 
+// decode uses data to set p with plan, the decodeFunc compiled for the type of p.
+func (d *decoder) decode(plan decodeFunc, p reflect.Value, data any) error {
 	// A Null value sets anything nullable to nil, and has no effect
 	// on anything else.
 	if data == nil {
@@ -69,310 +88,187 @@ func dataToReflectPointer(p reflect.Value, data any) error {
 		}
 		return nil
 	}
+	return plan(d, p, data)
+}
 
-	// Handle special types first.
-	switch p.Type() {
-	case typeOfByteSlice:
-		switch x := data.(type) {
-		case string:
-			b, err := base64.StdEncoding.DecodeString(x)
-			if err != nil {
-				return typeErr()
-			}
-			p.SetBytes(b)
-			return nil
-
-		case []byte:
-			p.SetBytes(x)
-			return nil
-
-		default:
-			return typeErr()
-		}
-
-	case typeOfGoTime:
-		switch x := data.(type) {
-		case time.Time:
-			p.Set(reflect.ValueOf(x))
-			return nil
-
-		case string:
-			ts, err := time.Parse(time.RFC3339, x)
-			if err != nil {
-				return typeErr()
-			}
-
-			p.Set(reflect.ValueOf(ts))
-			return nil
-
-		default:
-			return typeErr()
-		}
-	case typeOfLatLng:
-		switch x := data.(type) {
-		case latlng.LatLng:
-			p.Set(reflect.ValueOf(x))
-			return nil
-
-		case map[string]interface{}:
-			lat, ok := x["latitude"].(float64)
-			if !ok {
-				return errors.New("latitude is not a float64")
-			}
-			lng, ok := x["longitude"].(float64)
-			if !ok {
-				return errors.New("longitude is not a float64")
-			}
-			p.Set(reflect.ValueOf(latlng.LatLng{Latitude: lat, Longitude: lng}))
-			return nil
-
-		default:
-			return typeErr()
-		}
+func typeErr(p reflect.Value, data any) error {
+	return fmt.Errorf("cannot use value %T to populate %s ", data, p.Type())
+}
 
-	case typeOfUUID:
-		x, ok := data.(string)
-		if !ok {
-			return typeErr()
-		}
-		uuid, err := uuid.Parse(x)
+func decodeByteSlice(_ *decoder, p reflect.Value, data any) error {
+	switch x := data.(type) {
+	case string:
+		b, err := base64.StdEncoding.DecodeString(x)
 		if err != nil {
-			return fmt.Errorf("%v is not a valid UUID: %v", data, err)
-
+			return typeErr(p, data)
 		}
-		p.Set(reflect.ValueOf(uuid))
+		p.SetBytes(b)
+		return nil
+
+	case []byte:
+		p.SetBytes(x)
 		return nil
 
+	default:
+		return typeErr(p, data)
 	}
+}
 
-	switch p.Kind() {
-	case reflect.Bool:
-		x, ok := data.(bool)
-		if !ok {
-			return typeErr()
-		}
-		p.SetBool(x)
+func decodeGoTime(_ *decoder, p reflect.Value, data any) error {
+	switch x := data.(type) {
+	case time.Time:
+		p.Set(reflect.ValueOf(x))
+		return nil
 
-	case reflect.String:
-		x, ok := data.(string)
-		if !ok {
-			return typeErr()
-		}
-		p.SetString(x)
-
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		var i int64
-		switch x := data.(type) {
-		case int:
-			i = int64(x)
-		case int8:
-			i = int64(x)
-		case int16:
-			i = int64(x)
-		case int32:
-			i = int64(x)
-		case int64:
-			i = x
-		case float64:
-			i = int64(x)
-		default:
-			return typeErr()
+	case string:
+		ts, err := time.Parse(time.RFC3339, x)
+		if err != nil {
+			return typeErr(p, data)
 		}
 
-		if p.OverflowInt(i) {
-			return overflowErr(p, data)
-		}
-		p.SetInt(i)
-
-	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
-		var u uint64
-		switch x := data.(type) {
-		case uint8:
-			u = uint64(x)
-		case uint16:
-			u = uint64(x)
-		case uint32:
-			u = uint64(x)
-		case uint64:
-			u = x
-		default:
-			return typeErr()
-		}
+		p.Set(reflect.ValueOf(ts))
+		return nil
 
-		if p.OverflowUint(u) {
-			return overflowErr(p, data)
-		}
-		p.SetUint(u)
-
-	case reflect.Float32, reflect.Float64:
-		var f float64
-		switch x := data.(type) {
-		case float32:
-			f = float64(x)
-		case float64:
-			f = x
-		default:
-			return typeErr()
-		}
+	default:
+		return typeErr(p, data)
+	}
+}
 
-		if p.OverflowFloat(f) {
-			return overflowErr(p, data)
-		}
-		p.SetFloat(f)
+func decodeLatLng(_ *decoder, p reflect.Value, data any) error {
+	switch x := data.(type) {
+	case latlng.LatLng:
+		p.Set(reflect.ValueOf(x))
+		return nil
 
-	case reflect.Slice:
-		vals, ok := data.([]any)
+	case map[string]interface{}:
+		lat, ok := x["latitude"].(float64)
 		if !ok {
-			return typeErr()
-		}
-		vlen := p.Len()
-		xlen := len(vals)
-		// Make a slice of the right size, avoiding allocation if possible.
-		switch {
-		case vlen < xlen:
-			p.Set(reflect.MakeSlice(p.Type(), xlen, xlen))
-		case vlen > xlen:
-			p.SetLen(xlen)
+			return errors.New("latitude is not a float64")
 		}
-		return populateArray(p, vals, xlen)
-
-	case reflect.Array:
-		vals, ok := data.([]any)
+		lng, ok := x["longitude"].(float64)
 		if !ok {
-			return typeErr()
+			return errors.New("longitude is not a float64")
 		}
+		p.Set(reflect.ValueOf(latlng.LatLng{Latitude: lat, Longitude: lng}))
+		return nil
 
-		xlen := len(vals)
-		vlen := p.Len()
-		minlen := vlen
-		// Set extra elements to their zero value.
-		if vlen > xlen {
-			z := reflect.Zero(p.Type().Elem())
-			for i := xlen; i < vlen; i++ {
-				p.Index(i).Set(z)
-			}
-			minlen = xlen
-		}
-		return populateArray(p, vals, minlen)
-
-	case reflect.Map:
-		x, ok := data.(map[string]any)
-		if !ok {
-			return typeErr()
-		}
-
-		return populateMap(p, x)
-
-	case reflect.Ptr:
-		// If the pointer is nil, set it to a zero value.
-		if p.IsNil() {
-			p.Set(reflect.New(p.Type().Elem()))
-		}
-		return dataToReflectPointer(p.Elem(), data)
-
-	case reflect.Struct:
-		x, ok := data.(map[string]any)
-		if !ok {
-			return typeErr()
-		}
-		return populateStruct(p, x)
+	default:
+		return typeErr(p, data)
+	}
+}
 
-	case reflect.Interface:
-		if p.NumMethod() == 0 { // empty interface
-			// If p holds a pointer, set the pointer.
-			if !p.IsNil() && p.Elem().Kind() == reflect.Ptr {
-				return dataToReflectPointer(p.Elem(), data)
-			}
-			// Otherwise, create a fresh value.
-			p.Set(reflect.ValueOf(data))
-			return nil
-		}
-		// Any other kind of interface is an error.
-		fallthrough
+func decodeUUID(_ *decoder, p reflect.Value, data any) error {
+	x, ok := data.(string)
+	if !ok {
+		return typeErr(p, data)
+	}
+	uuid, err := uuid.Parse(x)
+	if err != nil {
+		return fmt.Errorf("%v is not a valid UUID: %v", data, err)
 
-	default:
-		return fmt.Errorf("cannot set type %s", p.Type())
 	}
+	p.Set(reflect.ValueOf(uuid))
 	return nil
 }
 
-// populateArray sets the first n elements of vr, which must be a slice or
-// array, to the corresponding elements of vals.
-func populateArray(vr reflect.Value, vals []any, n int) error {
-	for i := 0; i < n; i++ {
-		if err := dataToReflectPointer(vr.Index(i), vals[i]); err != nil {
-			return err
-		}
+func decodeBool(_ *decoder, p reflect.Value, data any) error {
+	x, ok := data.(bool)
+	if !ok {
+		return typeErr(p, data)
 	}
+	p.SetBool(x)
 	return nil
 }
 
-// populateMap sets the elements of vm, which must be a map, from the
-// corresponding elements of pm.
-//
-// Since a map value is not settable, this function always creates a new
-// element for each corresponding map key. Existing values of vm are
-// overwritten. This happens even if the map value is something like a pointer
-// to a struct, where we could in theory populate the existing struct value
-// instead of discarding it. This behavior matches encoding/json.
-func populateMap(vm reflect.Value, pm map[string]any) error {
-	t := vm.Type()
-	if t.Key().Kind() != reflect.String {
-		return errors.New("map key type is not string")
+func decodeString(_ *decoder, p reflect.Value, data any) error {
+	x, ok := data.(string)
+	if !ok {
+		return typeErr(p, data)
 	}
-	if vm.IsNil() {
-		vm.Set(reflect.MakeMap(t))
+	p.SetString(x)
+	return nil
+}
+
+func decodeInt(_ *decoder, p reflect.Value, data any) error {
+	var i int64
+	switch x := data.(type) {
+	case int:
+		i = int64(x)
+	case int8:
+		i = int64(x)
+	case int16:
+		i = int64(x)
+	case int32:
+		i = int64(x)
+	case int64:
+		i = x
+	case float64:
+		i = int64(x)
+	default:
+		return typeErr(p, data)
 	}
-	et := t.Elem()
-	for k, vproto := range pm {
-		el := reflect.New(et).Elem()
-		if err := dataToReflectPointer(el, vproto); err != nil {
-			return err
-		}
-		vm.SetMapIndex(reflect.ValueOf(k), el)
+
+	if p.OverflowInt(i) {
+		return overflowErr(p, data)
 	}
+	p.SetInt(i)
 	return nil
 }
 
-// populateStruct sets the fields of vs, which must be a struct, from
-// the matching elements of pm.
-func populateStruct(vs reflect.Value, data map[string]any) error {
-	fs, err := fieldCache.Fields(vs.Type())
-	if err != nil {
-		return err
+func decodeUint(_ *decoder, p reflect.Value, data any) error {
+	var u uint64
+	switch x := data.(type) {
+	case uint8:
+		u = uint64(x)
+	case uint16:
+		u = uint64(x)
+	case uint32:
+		u = uint64(x)
+	case uint64:
+		u = x
+	default:
+		return typeErr(p, data)
 	}
 
-	type match struct {
-		val any
-		f   *fields.Field
+	if p.OverflowUint(u) {
+		return overflowErr(p, data)
 	}
-	// Find best field matches
-	matched := make(map[string]match)
-	for k, field := range data {
-		f := fs.Match(k)
-		if f == nil {
-			continue
-		}
-		if _, ok := matched[f.Name]; ok {
-			// If multiple case insensitive fields match, the exact match
-			// should win.
-			if f.Name == k {
-				matched[k] = match{val: field, f: f}
-			}
-		} else {
-			matched[f.Name] = match{val: field, f: f}
-		}
+	p.SetUint(u)
+	return nil
+}
+
+func decodeFloat(_ *decoder, p reflect.Value, data any) error {
+	var f float64
+	switch x := data.(type) {
+	case float32:
+		f = float64(x)
+	case float64:
+		f = x
+	default:
+		return typeErr(p, data)
 	}
 
-	// Reflect values
-	for _, v := range matched {
-		f := v.f
-		val := v.val
+	if p.OverflowFloat(f) {
+		return overflowErr(p, data)
+	}
+	p.SetFloat(f)
+	return nil
+}
 
-		if err := dataToReflectPointer(vs.FieldByIndex(f.Index), val); err != nil {
-			return fmt.Errorf("%s.%s: %w", vs.Type(), f.Name, err)
+func decodeInterface(d *decoder, p reflect.Value, data any) error {
+	// If p holds a pointer, set the pointer.
+	if !p.IsNil() && p.Elem().Kind() == reflect.Ptr {
+		return d.dataToReflectPointer(p.Elem(), data)
+	}
+	if d.opts.merge && !p.IsNil() {
+		merged, ok := mergeValues(p.Elem().Interface(), data, d.opts.slices)
+		if ok {
+			p.Set(reflect.ValueOf(merged))
+			return nil
 		}
 	}
+	// Otherwise, create a fresh value.
+	p.Set(reflect.ValueOf(data))
 	return nil
 }
 
@@ -382,6 +278,53 @@ func overflowErr(v reflect.Value, x interface{}) error {
 
 var fieldCache = fields.NewCache(parseTag, nil, isLeafType)
 
+// matchField returns the field of fs that best matches the document field name, see fields.List.Match.
+// The field with the remain option never matches, it receives the document fields that match no other field.
+// Fields populated with document metadata never match either.
+func matchField(fs fields.List, name string) *fields.Field {
+	f := fs.Match(name)
+	if f == nil {
+		return nil
+	}
+	if opts, _ := f.ParsedTag.(tagOptions); opts.remain || opts.metadata != "" {
+		return nil
+	}
+	return f
+}
+
+// remainField returns the field of struct type t with the remain option, or nil if t has none.
+func remainField(t reflect.Type, fs fields.List) (*fields.Field, error) {
+	var remain *fields.Field
+	for i := range fs {
+		if opts, _ := fs[i].ParsedTag.(tagOptions); !opts.remain {
+			continue
+		}
+		if remain != nil {
+			return nil, fmt.Errorf("%s has more than one field with the remain option", t)
+		}
+		if ft := fs[i].Type; ft.Kind() != reflect.Map || ft.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%s.%s: remain option requires a map with string keys, got %s", t, fs[i].Name, ft)
+		}
+		remain = &fs[i]
+	}
+	return remain, nil
+}
+
+// fieldByIndex returns the nested field of struct v with the given index sequence,
+// allocating nil pointers to embedded or inlined structs along the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
 // isLeafType determines whether or not a type is a 'leaf type'
 // and should not be recursed into, but considered one field.
 func isLeafType(t reflect.Type) bool {
@@ -18,7 +18,6 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
-	"reflect"
 	"strconv"
 	"time"
 
@@ -60,82 +59,96 @@ var FirestoreSimpleDataTypes = []string{
 	protoNullTag,
 }
 
-// UnwrapFirestoreFields unwraps a map[string]any containing one or more nested Firestore protojson encoded fields and returns a Go map[string]any without Firestore protojson tags.
-func UnwrapFirestoreFields(input map[string]any) (map[string]any, error) {
-	if input == nil {
-		return nil, errors.New("nil map contents")
-	}
+// UnwrapFields unwraps the Firestore protojson encoded fields of a document, such as the Fields of a FirestoreDocument,
+// and returns a Go map[string]any without Firestore protojson tags.
+// Every value must be wrapped by exactly one type descriptor tag, so fields named after a tag, e.g. "mapValue", are unwrapped like any other field.
+func UnwrapFields(fields map[string]any) (map[string]any, error) {
+	return (&unwrapper{}).unwrapFields(fields)
+}
 
-	output := make(map[string]any, len(input))
-	emptyMap := make(map[string]interface{})
-	mapType := reflect.TypeOf(emptyMap)
+// UnwrapFieldsInPlace is like UnwrapFields, but consumes fields: the wrapped values of its maps and arrays are replaced
+// by their unwrapped values, and fields itself is returned. Unwrapping a large document decoded by json.Unmarshal this way
+// allocates next to nothing besides the values that need converting, such as integers and timestamps.
+//
+// The input must not be used after the call, not even when an error is returned, since it may be partially unwrapped.
+// It must not share maps or arrays with other data either.
+func UnwrapFieldsInPlace(fields map[string]any) (map[string]any, error) {
+	return (&unwrapper{inPlace: true}).unwrapFields(fields)
+}
 
-	for k, val := range input {
-		// The value must be a map[string]interface{} to be valid Firestore protojson data
-		vType := reflect.TypeOf(val)
-		if vType != mapType {
-			return nil, fmt.Errorf("invalid input, expecting *map[string]any, but received %T", val)
-		}
+// UnwrapFieldsWithLimits is like UnwrapFields, but returns a *LimitError as soon as the fields exceed one of the limits.
+// Limits are checked before values are allocated or decoded, use it to unwrap untrusted payloads.
+func UnwrapFieldsWithLimits(fields map[string]any, limits Limits) (map[string]any, error) {
+	return (&unwrapper{limits: &limits}).unwrapFields(fields)
+}
+
+// UnwrapValue unwraps a single Firestore protojson encoded value, such as {"stringValue": "foo"} or {"mapValue": {"fields": {...}}},
+// and returns it as a native Go value.
+func UnwrapValue(value any) (any, error) {
+	return (&unwrapper{}).unwrapValue(value, false)
+}
 
-		// handle less common cases first
-		if len(input) == 1 {
-			if k == protoMapTag {
-				// if the document only contains a single map without a title descriptor, we can return the map directly
-				x, err := unwrapMap(val)
-				if err != nil {
-					return nil, err
-				}
-
-				return x, nil
-			} else if k == protoArrayTag {
-				// when a document contains an array the immediate children won't have a title descriptor, so no need to unwrap the title
-				x, err := unwrapArray(val)
-				if err != nil {
-					return nil, err
-				}
-
-				output[k] = x
-				return output, nil
+// UnwrapFirestoreFields unwraps a map[string]any containing one or more nested Firestore protojson encoded fields and returns a Go map[string]any without Firestore protojson tags.
+//
+// Deprecated: UnwrapFirestoreFields guesses whether its input is a document or a single value. An input with a single key named "mapValue"
+// is unwrapped as a map value, and an input with a single key named "arrayValue" as a field holding an array value, which misdecodes documents
+// with a single field of that name. Use UnwrapFields to unwrap the fields of a document and UnwrapValue to unwrap a single value.
+//
+// Unlike UnwrapFields, it also accepts array elements that are the fields of a map without a mapValue descriptor.
+func UnwrapFirestoreFields(input map[string]any) (map[string]any, error) {
+	u := &unwrapper{lenient: true}
+	if len(input) == 1 {
+		if val, ok := input[protoMapTag]; ok {
+			// the input is a single map without a title descriptor, return the map directly
+			return u.unwrapMap(val)
+		}
+		if val, ok := input[protoArrayTag]; ok {
+			x, err := u.unwrapArray(val)
+			if err != nil {
+				return nil, err
 			}
+			return map[string]any{protoArrayTag: x}, nil
 		}
+	}
 
-		// usually the top level of the input map is a title descriptor, we evaluate the protojson tags in the subvalues before unwrapping our data
-		for kk, vv := range val.(map[string]interface{}) {
-			// Process data types that don't contain nested data first
-			if kk != protoMapTag && kk != protoArrayTag {
-				x, err := unwrapFlatValue(val)
-				if err != nil {
-					return nil, err
-				}
-				output[k] = x
-
-				continue
-			}
+	return u.unwrapFields(input)
+}
 
-			// recursively process maps
-			if kk == protoMapTag {
-				x, err := unwrapMap(vv)
-				if err != nil {
-					return nil, err
-				}
+// wrappedTag returns the protojson type descriptor tag of a single Firestore protojson encoded value and the value it wraps.
+// ok is false if value is not a map containing exactly one known type descriptor tag.
+func wrappedTag(value any) (tag string, inner any, ok bool) {
+	m, isMap := value.(map[string]any)
+	if !isMap || len(m) != 1 {
+		return "", nil, false
+	}
 
-				output[k] = x
+	for k, v := range m {
+		if k == protoMapTag || k == protoArrayTag {
+			return k, v, true
+		}
+		for _, t := range FirestoreFlatDataTypes {
+			if k == t {
+				return k, v, true
 			}
+		}
+	}
 
-			// recursively process arrays as slices
-			if kk == protoArrayTag {
-				x, err := unwrapArray(vv)
-				if err != nil {
-					return nil, err
-				}
+	return "", nil, false
+}
 
-				output[k] = x
-			}
+// splitWrapped returns the protojson type descriptor tag of a single Firestore protojson encoded value and the value it wraps,
+// or an error if value is not a map containing exactly one known type descriptor tag.
+// When bareMap is set, a map without a type descriptor tag is returned as the fields of a mapValue. Only array elements of
+// documents unwrapped by UnwrapFirestoreFields and the methods of FirestoreDocument are parsed this way, for compatibility.
+func splitWrapped(value any, bareMap bool) (tag string, inner any, err error) {
+	tag, inner, ok := wrappedTag(value)
+	if !ok {
+		if m, isMap := value.(map[string]any); isMap && bareMap {
+			return protoMapTag, map[string]any{"fields": m}, nil
 		}
-
+		return "", nil, fmt.Errorf("invalid Firestore protojson value, expecting a single type descriptor tag, got: %v", value)
 	}
-
-	return output, nil
+	return tag, inner, nil
 }
 
 // unwrapFlatValue unwraps shallow Firestore data types (i.e. those without nested data structures)
@@ -187,6 +200,83 @@ func unwrapFlatValue(value any) (any, error) {
 
 // unwrapMap returns the values nested within a Firestore json encoded map
 func unwrapMap(value any) (map[string]any, error) {
+	return (&unwrapper{lenient: true}).unwrapMap(value)
+}
+
+// unwrapArray returns the array values nested within a Firestore json encoded array
+func unwrapArray(array any) ([]any, error) {
+	return (&unwrapper{lenient: true}).unwrapArray(array)
+}
+
+// An unwrapper unwraps Firestore protojson encoded values, and enforces limits if they are set.
+type unwrapper struct {
+	limits  *Limits // no limits are enforced when nil
+	inPlace bool    // replace the wrapped values of the input maps and arrays instead of allocating new ones
+	lenient bool    // accept array elements that are bare maps of fields, see splitWrapped
+	depth   int     // depth of the map or array whose values are being unwrapped, top level fields have depth 1
+	fields  int     // number of map fields unwrapped so far
+	size    int     // storage size of the values unwrapped so far
+}
+
+// unwrapFields unwraps the fields of a document or map.
+func (u *unwrapper) unwrapFields(fields map[string]any) (map[string]any, error) {
+	if fields == nil {
+		return nil, errors.New("nil map contents")
+	}
+	if u.limits != nil {
+		if err := u.enterFields(len(fields)); err != nil {
+			return nil, err
+		}
+		defer u.leave()
+	}
+
+	output := fields
+	if !u.inPlace {
+		output = make(map[string]any, len(fields))
+	}
+	for k, val := range fields {
+		if u.limits != nil {
+			if err := u.addSize(stringSize(k)); err != nil {
+				return nil, err
+			}
+		}
+		x, err := u.unwrapValue(val, false)
+		if err != nil {
+			var le *LimitError
+			if errors.As(err, &le) {
+				le.Path = append(FieldPath{k}, le.Path...)
+				return nil, le
+			}
+			return nil, fmt.Errorf("field %s: %w", quoteSegment(k), err)
+		}
+		output[k] = x
+	}
+	return output, nil
+}
+
+// unwrapValue unwraps a single value wrapped by a type descriptor tag, elem is set for array elements.
+func (u *unwrapper) unwrapValue(value any, elem bool) (any, error) {
+	tag, inner, err := splitWrapped(value, elem && u.lenient)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case protoMapTag:
+		return u.unwrapMap(inner)
+	case protoArrayTag:
+		return u.unwrapArray(inner)
+	}
+	if u.limits != nil {
+		if err := u.addValueSize(tag, inner); err != nil {
+			return nil, err
+		}
+	}
+	return unwrapFlatValue(value)
+}
+
+// unwrapMap returns the values nested within a Firestore json encoded map
+func (u *unwrapper) unwrapMap(value any) (map[string]any, error) {
 	m, ok := value.(map[string]any)
 	if !ok {
 		return nil, fmt.Errorf("unwrapMap error, Firestore map is expected to be a map[string]interface{} got: %T", value)
@@ -205,15 +295,11 @@ func unwrapMap(value any) (map[string]any, error) {
 		return nil, fmt.Errorf("unwrapMap erro, Firestore map fields are expected to be a map[string]interface{} got: %T", value)
 	}
 
-	subValues, err := UnwrapFirestoreFields(mv)
-	if err != nil {
-		return nil, err
-	}
-	return subValues, nil
+	return u.unwrapFields(mv)
 }
 
 // unwrapArray returns the array values nested within a Firestore json encoded array
-func unwrapArray(array any) ([]any, error) {
+func (u *unwrapper) unwrapArray(array any) ([]any, error) {
 	am, ok := array.(map[string]any)
 	if !ok {
 		return nil, fmt.Errorf("unwrapArray error, Firestore array is expected to be a map[string]interface{}")
@@ -232,9 +318,18 @@ func unwrapArray(array any) ([]any, error) {
 	if !ok {
 		return nil, fmt.Errorf("unwrapArray error, \"values\" does not contain an array of values")
 	}
+	if u.limits != nil {
+		if err := u.enterArray(len(va)); err != nil {
+			return nil, err
+		}
+		defer u.leave()
+	}
 
 	// create new array and populate it with unwrapped subvalues
-	outputArray := make([]any, len(va))
+	outputArray := va
+	if !u.inPlace {
+		outputArray = make([]any, len(va))
+	}
 
 	for i, val := range va {
 		mapVal, ok := val.(map[string]interface{})
@@ -242,35 +337,15 @@ func unwrapArray(array any) ([]any, error) {
 			return nil, fmt.Errorf("unwrapArray error, array can only contain values encoded as map[string]interface{}")
 		}
 
-		// If the array value contains only a single map key, and it matches the tag for a flat data type, we can unwrap it directly
-		isFlatDataType := false
-		if len(mapVal) == 1 {
-			for _, key := range FirestoreFlatDataTypes {
-				if _, ok := mapVal[key]; ok {
-					isFlatDataType = true
-
-					// Extract the flat value from the protojson map
-					x, err := unwrapFlatValue(mapVal)
-					if err != nil {
-						return nil, fmt.Errorf("unwrapArray error unwrapping flat value: %v", err)
-					}
-					outputArray[i] = x
-					break
-				}
-			}
-			if isFlatDataType {
-				continue
-			}
-		}
-
-		// Recursively unwrap arrays and maps containing nested data structures inside this array element
-		output, err := UnwrapFirestoreFields(mapVal)
+		x, err := u.unwrapValue(mapVal, true)
 		if err != nil {
-			return nil, err
+			var le *LimitError
+			if errors.As(err, &le) {
+				return nil, le
+			}
+			return nil, fmt.Errorf("unwrapArray error at index %d: %w", i, err)
 		}
-		outputArray[i] = output
-
-		continue
+		outputArray[i] = x
 	}
 
 	return outputArray, nil
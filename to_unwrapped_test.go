@@ -1,6 +1,9 @@
 package firestruct
 
 import (
+	"encoding/json"
+	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/bennovw/firestruct/internal/testutil"
@@ -94,3 +97,251 @@ func TestUnwrapFirestoreFields(t *testing.T) {
 	}
 
 }
+
+func TestUnwrapFields(t *testing.T) {
+	thisFunctionName := "UnwrapFields"
+	tests := []UnwrappedTableTest{
+		{
+			Name:     "Firestore Nested Fields",
+			Input:    testutil.WrapBareMaps(testutil.TestFirebaseDocFields[12]),
+			Expected: testutil.FlattenedMapResults[12],
+		},
+		{
+			Name: "field named mapValue",
+			Input: map[string]any{
+				"mapValue": map[string]any{"mapValue": map[string]any{"fields": map[string]any{
+					"mapValue": map[string]any{"stringValue": "nested"},
+				}}},
+			},
+			Expected: map[string]any{"mapValue": map[string]any{"mapValue": "nested"}},
+		},
+		{
+			Name: "field named arrayValue",
+			Input: map[string]any{
+				"arrayValue": map[string]any{"arrayValue": map[string]any{"values": []any{
+					map[string]any{"integerValue": "1"},
+					map[string]any{"mapValue": map[string]any{"fields": map[string]any{"name": map[string]any{"stringValue": "map"}}}},
+				}}},
+			},
+			Expected: map[string]any{"arrayValue": []any{1, map[string]any{"name": "map"}}},
+		},
+		{
+			Name:     "field named stringValue",
+			Input:    map[string]any{"stringValue": map[string]any{"booleanValue": true}},
+			Expected: map[string]any{"stringValue": true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := UnwrapFields(test.Input)
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+
+			testutil.IsDeepEqualTest(t, result, test.Expected, thisFunctionName, test.Name)
+
+			doc := FirestoreDocument{Fields: test.Input}
+			m, err := doc.ToMap()
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", "ToMap", test.Name, err)
+			}
+			testutil.IsDeepEqualTest(t, m, test.Expected, "ToMap", test.Name)
+		})
+	}
+
+	errorTests := []struct {
+		Name  string
+		Input map[string]any
+	}{
+		{Name: "nil", Input: nil},
+		{Name: "single value", Input: testutil.TestFirebaseDocFields[10]},
+		{Name: "unwrapped value", Input: map[string]any{"name": "foo"}},
+		{Name: "two tags", Input: map[string]any{"name": map[string]any{"stringValue": "foo", "booleanValue": true}}},
+		{Name: "bare map array element", Input: map[string]any{"a": map[string]any{"arrayValue": map[string]any{"values": []any{
+			map[string]any{"name": map[string]any{"stringValue": "foo"}},
+		}}}}},
+	}
+	for _, test := range errorTests {
+		t.Run(test.Name, func(t *testing.T) {
+			if _, err := UnwrapFields(test.Input); err == nil {
+				t.Errorf("%v() test \"%v\" expected an error", thisFunctionName, test.Name)
+			}
+		})
+	}
+}
+
+// decodeJSONFields returns a copy of fields decoded by json.Unmarshal, like the fields of a decoded event.
+func decodeJSONFields(t testing.TB, fields map[string]any) map[string]any {
+	b, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestUnwrapFieldsInPlace(t *testing.T) {
+	thisFunctionName := "UnwrapFieldsInPlace"
+	for i, fields := range testutil.TestFirebaseDocFields {
+		name := fmt.Sprintf("fixture %d", i)
+		t.Run(name, func(t *testing.T) {
+			expected, expectedErr := UnwrapFields(decodeJSONFields(t, testutil.WrapBareMaps(fields)))
+
+			input := decodeJSONFields(t, testutil.WrapBareMaps(fields))
+			result, err := UnwrapFieldsInPlace(input)
+			if (err == nil) != (expectedErr == nil) {
+				t.Fatalf("%v() test \"%v\" returned error: %v, UnwrapFields returned error: %v", thisFunctionName, name, err, expectedErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%v\n%v", thisFunctionName, name, result, expected)
+			}
+			if reflect.ValueOf(result).UnsafePointer() != reflect.ValueOf(input).UnsafePointer() {
+				t.Errorf("%v() test \"%v\" did not reuse the input map", thisFunctionName, name)
+			}
+		})
+	}
+
+	doc := FirestoreDocument{Fields: decodeJSONFields(t, testutil.TestFirebaseDocFields[12])}
+	m, err := doc.ToMapInPlace()
+	if err != nil {
+		t.Errorf("%v() returned error: %v", "ToMapInPlace", err)
+	}
+	if doc.Fields != nil {
+		t.Errorf("%v() did not consume the document fields", "ToMapInPlace")
+	}
+	expected, _ := UnwrapFirestoreFields(decodeJSONFields(t, testutil.TestFirebaseDocFields[12]))
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("%v() output does not match expected data:\n%v\n%v", "ToMapInPlace", m, expected)
+	}
+}
+
+func BenchmarkUnwrapFields(b *testing.B) {
+	benchmarkUnwrap(b, UnwrapFields)
+}
+
+func BenchmarkUnwrapFieldsInPlace(b *testing.B) {
+	benchmarkUnwrap(b, UnwrapFieldsInPlace)
+}
+
+// benchmarkUnwrap unwraps a large document decoded by json.Unmarshal with unwrap, decoding excluded.
+func benchmarkUnwrap(b *testing.B, unwrap func(map[string]any) (map[string]any, error)) {
+	doc := make(map[string]any)
+	for i := 0; i < 100; i++ {
+		doc[fmt.Sprintf("field%d", i)] = map[string]any{"mapValue": map[string]any{"fields": testutil.TestFirebaseDocFields[12]}}
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		input := decodeJSONFields(b, doc)
+		b.StartTimer()
+		if _, err := unwrap(input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestUnwrapValue(t *testing.T) {
+	thisFunctionName := "UnwrapValue"
+	tests := []struct {
+		Name     string
+		Input    any
+		Expected any
+	}{
+		{
+			Name:     "Firestore Map",
+			Input:    testutil.TestFirebaseDocFields[10],
+			Expected: testutil.FlattenedMapResults[10],
+		},
+		{
+			Name:     "array",
+			Input:    map[string]any{"arrayValue": map[string]any{"values": []any{map[string]any{"stringValue": "a"}}}},
+			Expected: []any{"a"},
+		},
+		{
+			Name:     "string",
+			Input:    map[string]any{"stringValue": "a"},
+			Expected: "a",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := UnwrapValue(test.Input)
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+
+			if !reflect.DeepEqual(result, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%v\n%v", thisFunctionName, test.Name, result, test.Expected)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		Name  string
+		Input any
+	}{
+		{Name: "unwrapped", Input: "a"},
+		{Name: "document fields", Input: testutil.TestFirebaseDocFields[11]},
+		{Name: "empty", Input: map[string]any{}},
+	}
+	for _, test := range errorTests {
+		t.Run(test.Name, func(t *testing.T) {
+			if _, err := UnwrapValue(test.Input); err == nil {
+				t.Errorf("%v() test \"%v\" expected an error", thisFunctionName, test.Name)
+			}
+		})
+	}
+}
+
+func TestBareMapArrayElements(t *testing.T) {
+	array := map[string]any{"arrayValue": map[string]any{"values": []any{
+		map[string]any{"name": map[string]any{"stringValue": "foo"}},
+	}}}
+	fields := map[string]any{"a": array}
+	expected := map[string]any{"a": []any{map[string]any{"name": "foo"}}}
+
+	// UnwrapFirestoreFields and the methods of FirestoreDocument accept the fields of a map as array elements
+	result, err := UnwrapFirestoreFields(fields)
+	if err != nil || !reflect.DeepEqual(result, expected) {
+		t.Errorf("%v() returned %v, error: %v", "UnwrapFirestoreFields", result, err)
+	}
+	doc := FirestoreDocument{Fields: fields}
+	if m, err := doc.ToMap(); err != nil || !reflect.DeepEqual(m, expected) {
+		t.Errorf("%v() returned %v, error: %v", "ToMap", m, err)
+	}
+	if x, err := doc.Get(FieldPath{"a"}); err != nil || !reflect.DeepEqual(x, expected["a"]) {
+		t.Errorf("%v() returned %v, error: %v", "Get", x, err)
+	}
+	if values, err := doc.Values(); err != nil || !reflect.DeepEqual(values.Interface(), expected) {
+		t.Errorf("%v() returned %v, error: %v", "Values", values, err)
+	}
+	if b, err := doc.MarshalPlainJSON(); err != nil || string(b) != `{"a":[{"name":"foo"}]}` {
+		t.Errorf("%v() returned %s, error: %v", "MarshalPlainJSON", b, err)
+	}
+
+	// The other functions require every array element to be wrapped by a type descriptor tag
+	if _, err := UnwrapFields(fields); err == nil {
+		t.Errorf("%v() expected an error", "UnwrapFields")
+	}
+	if _, err := UnwrapValue(array); err == nil {
+		t.Errorf("%v() expected an error", "UnwrapValue")
+	}
+	if _, err := ParseFields(fields); err == nil {
+		t.Errorf("%v() expected an error", "ParseFields")
+	}
+	if _, err := InferSchema(fields); err == nil {
+		t.Errorf("%v() expected an error", "InferSchema")
+	}
+	if _, err := GenerateStruct("Doc", []map[string]any{fields}); err == nil {
+		t.Errorf("%v() expected an error", "GenerateStruct")
+	}
+}
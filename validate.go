@@ -0,0 +1,397 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/bennovw/firestruct/internal/fields"
+)
+
+// A Validator is a type that validates itself. Validate and decoding with WithValidation call the Validate method
+// of every struct implementing Validator, after the rules of its fields were checked.
+// Returned ValidationErrors are merged into the reported errors with their paths relative to the struct,
+// other errors are reported as a ValidationError with the rule "Validate".
+// Struct values that are not addressable, such as the values of a map, are copied to call a Validate method with a pointer receiver.
+type Validator interface {
+	Validate() error
+}
+
+// A ValidationError reports a value that does not satisfy a validation rule.
+type ValidationError struct {
+	Path    FieldPath // Firestore path of the field, elements of arrays share the path of the array
+	Rule    string    // the rule that failed, e.g. "min", or "Validate" for errors returned by a Validator
+	Message string
+	Err     error // the error returned by a Validator, if any
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("field %s %s", e.Path, e.Message)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors lists every validation rule that failed.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// WithValidation validates the target after it was populated, see Validate.
+func WithValidation() DecodeOption {
+	return func(o *decodeOptions) {
+		o.validate = true
+	}
+}
+
+// Validate checks the rules in the validate struct tags of v, a struct or pointer to a struct, and calls the Validate method
+// of every struct implementing Validator. Nested structs, and the elements of slices and maps, are validated recursively.
+// Failed rules are reported as ValidationErrors, and invalid rules as a plain error.
+//
+// The validate tag holds a comma separated list of rules:
+//
+//	required       the value is not the zero value, or a nil pointer, slice or map
+//	min=n, max=n   numbers are at least or at most n, strings, slices and maps have at least or at most n elements
+//	len=n          strings, slices and maps have exactly n elements, strings are measured in characters
+//	oneof=a b c    strings and integers are one of the space separated values
+//	regex=expr     strings match the regular expression, it must be the last rule as expr may contain commas
+//
+// For example:
+//
+//	type User struct {
+//		Name  string `firestore:"name" validate:"required,max=64"`
+//		Role  string `firestore:"role" validate:"oneof=admin member"`
+//		Email string `firestore:"email,omitempty" validate:"regex=^[^@]+@[^@]+$"`
+//	}
+//
+// Rules other than required are not checked for nil pointers, nor for zero values of fields tagged omitempty.
+func Validate(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return errors.New("Validate error, nil pointer")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("Validate error, expecting a struct got: %T", v)
+	}
+
+	vd := &validator{}
+	if err := vd.validateValue(rv, nil); err != nil {
+		return err
+	}
+	if len(vd.errs) > 0 {
+		return vd.errs
+	}
+	return nil
+}
+
+// A validationRule is a single parsed rule of a validate tag.
+type validationRule struct {
+	name  string
+	param string
+	num   float64        // parameter of min, max and len
+	oneof []string       // parameter of oneof
+	re    *regexp.Regexp // parameter of regex
+}
+
+// parseValidateTag parses the rules of a validate tag.
+func parseValidateTag(tag string) ([]validationRule, error) {
+	var rules []validationRule
+	for tag != "" {
+		var s string
+		if strings.HasPrefix(tag, "regex=") {
+			s, tag = tag, ""
+		} else {
+			s, tag, _ = strings.Cut(tag, ",")
+		}
+
+		name, param, hasParam := strings.Cut(s, "=")
+		r := validationRule{name: name, param: param}
+		switch name {
+		case "required":
+			if hasParam {
+				return nil, fmt.Errorf("validate rule %q does not take a parameter", name)
+			}
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return nil, fmt.Errorf("validate rule %q requires a number: %v", name, err)
+			}
+			r.num = n
+		case "oneof":
+			r.oneof = strings.Fields(param)
+			if len(r.oneof) == 0 {
+				return nil, fmt.Errorf("validate rule %q requires at least one value", name)
+			}
+		case "regex":
+			re, err := regexp.Compile(param)
+			if err != nil {
+				return nil, fmt.Errorf("validate rule %q: %v", name, err)
+			}
+			r.re = re
+		default:
+			return nil, fmt.Errorf("unknown validate rule: %q", s)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// validateRules caches the parsed validate tags of struct types, by reflect.Type. They are parsed when a struct is first
+// validated rather than by the field cache, so decoding ignores validate tags meant for other validation packages.
+var validateRules sync.Map
+
+// structRules returns the rules of the validate tags of fs, the fields of struct type t.
+func structRules(t reflect.Type, fs []fields.Field) ([][]validationRule, error) {
+	if rules, ok := validateRules.Load(t); ok {
+		return rules.([][]validationRule), nil
+	}
+	rules := make([][]validationRule, len(fs))
+	for i, f := range fs {
+		var err error
+		rules[i], err = parseValidateTag(t.FieldByIndex(f.Index).Tag.Get("validate"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	validateRules.Store(t, rules)
+	return rules, nil
+}
+
+var typeOfValidator = reflect.TypeOf((*Validator)(nil)).Elem()
+
+// validator collects the validation errors of a value.
+type validator struct {
+	errs ValidationErrors
+}
+
+// validateValue validates the structs nested in v.
+func (vd *validator) validateValue(v reflect.Value, path FieldPath) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			return vd.validateValue(v.Elem(), path)
+		}
+
+	case reflect.Struct:
+		if isLeafType(v.Type()) {
+			return nil
+		}
+		return vd.validateStruct(v, path)
+
+	case reflect.Slice, reflect.Array:
+		if v.Type() == typeOfByteSlice || v.Type() == typeOfUUID {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := vd.validateValue(v.Index(i), path); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			fp := path
+			if iter.Key().Kind() == reflect.String {
+				fp = appendFieldPath(path, iter.Key().String())
+			}
+			if err := vd.validateValue(iter.Value(), fp); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateStruct checks the rules of the fields of a struct, validates nested values and calls its Validate method.
+func (vd *validator) validateStruct(v reflect.Value, path FieldPath) error {
+	fields, err := fieldCache.Fields(v.Type())
+	if err != nil {
+		return err
+	}
+
+	rules, err := structRules(v.Type(), fields)
+	if err != nil {
+		return err
+	}
+
+	for i, f := range fields {
+		fv, err := v.FieldByIndexErr(f.Index)
+		if err != nil {
+			// a field of a nil embedded struct pointer
+			continue
+		}
+		fp := appendFieldPath(path, f.Name)
+
+		opts, _ := f.ParsedTag.(tagOptions)
+		for _, r := range rules[i] {
+			if err := vd.checkRule(r, fv, fp, opts.omitEmpty); err != nil {
+				return err
+			}
+		}
+		if err := vd.validateValue(fv, fp); err != nil {
+			return err
+		}
+	}
+
+	vd.callValidator(v, path)
+	return nil
+}
+
+// callValidator calls the Validate method of v if it implements Validator. A Validate method with a pointer receiver
+// is called on a copy of v if v is not addressable, such as the struct values of a map.
+func (vd *validator) callValidator(v reflect.Value, path FieldPath) {
+	var err error
+	switch {
+	case v.CanAddr() && v.Addr().Type().Implements(typeOfValidator):
+		err = v.Addr().Interface().(Validator).Validate()
+	case v.Type().Implements(typeOfValidator):
+		err = v.Interface().(Validator).Validate()
+	case reflect.PtrTo(v.Type()).Implements(typeOfValidator):
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		err = p.Interface().(Validator).Validate()
+	default:
+		return
+	}
+	if err == nil {
+		return
+	}
+
+	var verrs ValidationErrors
+	var verr *ValidationError
+	switch {
+	case errors.As(err, &verrs):
+	case errors.As(err, &verr):
+		verrs = ValidationErrors{verr}
+	default:
+		vd.errs = append(vd.errs, &ValidationError{Path: path, Rule: "Validate", Message: err.Error(), Err: err})
+		return
+	}
+	for _, e := range verrs {
+		rel := *e
+		rel.Path = append(append(FieldPath{}, path...), e.Path...)
+		vd.errs = append(vd.errs, &rel)
+	}
+}
+
+// checkRule checks a single rule against a field value. Failed rules are collected, the returned error reports rules
+// that cannot be applied to the field's type.
+func (vd *validator) checkRule(r validationRule, v reflect.Value, path FieldPath, omitEmpty bool) error {
+	fail := func(format string, args ...any) {
+		vd.errs = append(vd.errs, &ValidationError{Path: path, Rule: r.name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if r.name == "required" {
+		if v.IsZero() {
+			fail("is required")
+		}
+		return nil
+	}
+	if omitEmpty && v.IsZero() {
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	unsupported := func() error {
+		return fmt.Errorf("validate rule %q of field %s is not supported for type %s", r.name, path, v.Type())
+	}
+
+	switch r.name {
+	case "min", "max", "len":
+		n, isLength := 0.0, true
+		switch v.Kind() {
+		case reflect.String:
+			n = float64(utf8.RuneCountInString(v.String()))
+		case reflect.Slice, reflect.Array, reflect.Map:
+			n = float64(v.Len())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, isLength = float64(v.Int()), false
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			n, isLength = float64(v.Uint()), false
+		case reflect.Float32, reflect.Float64:
+			n, isLength = v.Float(), false
+		default:
+			return unsupported()
+		}
+
+		subject := "must be"
+		if isLength {
+			subject = "length must be"
+		} else if r.name == "len" {
+			return unsupported()
+		}
+		switch {
+		case r.name == "min" && n < r.num:
+			fail("%s at least %v", subject, r.param)
+		case r.name == "max" && n > r.num:
+			fail("%s at most %v", subject, r.param)
+		case r.name == "len" && n != r.num:
+			fail("%s %v", subject, r.param)
+		}
+
+	case "oneof":
+		var s string
+		switch v.Kind() {
+		case reflect.String:
+			s = v.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			s = strconv.FormatInt(v.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			s = strconv.FormatUint(v.Uint(), 10)
+		default:
+			return unsupported()
+		}
+		for _, allowed := range r.oneof {
+			if s == allowed {
+				return nil
+			}
+		}
+		fail("must be one of %s", strings.Join(r.oneof, ", "))
+
+	case "regex":
+		if v.Kind() != reflect.String {
+			return unsupported()
+		}
+		if !r.re.MatchString(v.String()) {
+			fail("must match %s", r.param)
+		}
+	}
+	return nil
+}
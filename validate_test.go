@@ -0,0 +1,199 @@
+package firestruct
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type validateTestAddress struct {
+	City string `firestore:"city" validate:"required"`
+	Zip  string `firestore:"zip,omitempty" validate:"regex=^[0-9]{4,5}$"`
+}
+
+type validateTestUser struct {
+	Name    string                         `firestore:"name" validate:"required,max=5"`
+	Role    string                         `firestore:"role" validate:"oneof=admin member"`
+	Age     *int64                         `firestore:"age" validate:"min=18,max=130"`
+	Level   int                            `firestore:"level" validate:"oneof=1 2 3"`
+	Tags    []string                       `firestore:"tags" validate:"min=1,max=3"`
+	Code    string                         `firestore:"code,omitempty" validate:"len=3"`
+	Address validateTestAddress            `firestore:"address"`
+	Others  []validateTestAddress          `firestore:"others"`
+	ByName  map[string]validateTestAddress `firestore:"byName"`
+}
+
+// Validate reports an error for users named "root"
+func (u *validateTestUser) Validate() error {
+	if u.Name == "root" {
+		return errors.New("root is reserved")
+	}
+	return nil
+}
+
+type validateTestAccount struct {
+	Owner validateTestOwner `firestore:"owner"`
+}
+
+type validateTestTeam struct {
+	Members map[string]validateTestUser `firestore:"members"`
+}
+
+type validateTestOwner struct {
+	ID string `firestore:"id"`
+}
+
+// Validate reports ValidationErrors relative to the owner
+func (o validateTestOwner) Validate() error {
+	if o.ID == "" {
+		return ValidationErrors{{Path: FieldPath{"id"}, Rule: "custom", Message: "is missing"}}
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	thisFunctionName := "Validate"
+	age := int64(12)
+	tests := []struct {
+		Name     string
+		Input    any
+		Expected []string
+	}{
+		{
+			Name: "valid",
+			Input: &validateTestUser{
+				Name: "jane", Role: "admin", Level: 2, Tags: []string{"a"},
+				Address: validateTestAddress{City: "Ghent", Zip: "9000"},
+			},
+		},
+		{
+			Name: "invalid",
+			Input: &validateTestUser{
+				Name: "johnny", Role: "guest", Age: &age, Level: 4, Code: "ab",
+				Address: validateTestAddress{Zip: "x"},
+				Others:  []validateTestAddress{{City: "Ghent"}, {}},
+				ByName:  map[string]validateTestAddress{"home": {}},
+			},
+			Expected: []string{
+				"field name length must be at most 5",
+				"field role must be one of admin, member",
+				"field age must be at least 18",
+				"field level must be one of 1, 2, 3",
+				"field tags length must be at least 1",
+				"field code length must be 3",
+				"field address.city is required",
+				"field address.zip must match ^[0-9]{4,5}$",
+				"field others.city is required",
+				"field byName.home.city is required",
+			},
+		},
+		{
+			Name:     "validator",
+			Input:    &validateTestUser{Name: "root", Role: "member", Level: 1, Tags: []string{"a"}, Address: validateTestAddress{City: "Ghent"}},
+			Expected: []string{"root is reserved"},
+		},
+		{
+			Name:     "pointer validator of a struct value",
+			Input:    validateTestUser{Name: "root", Role: "member", Level: 1, Tags: []string{"a"}, Address: validateTestAddress{City: "Ghent"}},
+			Expected: []string{"root is reserved"},
+		},
+		{
+			Name: "pointer validator of map values",
+			Input: &validateTestTeam{Members: map[string]validateTestUser{
+				"lead": {Name: "root", Role: "member", Level: 1, Tags: []string{"a"}, Address: validateTestAddress{City: "Ghent"}},
+			}},
+			Expected: []string{"field members.lead root is reserved"},
+		},
+		{
+			Name:     "nested validator errors",
+			Input:    validateTestAccount{},
+			Expected: []string{"field owner.id is missing"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := Validate(test.Input)
+			var result []string
+			var verrs ValidationErrors
+			if errors.As(err, &verrs) {
+				for _, e := range verrs {
+					result = append(result, e.Error())
+				}
+			} else if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+			if !reflect.DeepEqual(result, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%q\n%q", thisFunctionName, test.Name, result, test.Expected)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		Name  string
+		Input any
+	}{
+		{Name: "not a struct", Input: 1},
+		{Name: "nil pointer", Input: (*validateTestUser)(nil)},
+		{Name: "unsupported rule type", Input: &struct {
+			B bool `validate:"min=1"`
+		}{B: true}},
+		{Name: "unknown rule", Input: &struct {
+			S string `validate:"email"`
+		}{}},
+		{Name: "invalid regex", Input: &struct {
+			S string `validate:"regex=("`
+		}{}},
+	}
+	for _, test := range errorTests {
+		err := Validate(test.Input)
+		var verrs ValidationErrors
+		if err == nil || errors.As(err, &verrs) {
+			t.Errorf("%v() test \"%v\" expected a non-validation error, got: %v", thisFunctionName, test.Name, err)
+		}
+	}
+}
+
+func TestDataToWithValidation(t *testing.T) {
+	thisFunctionName := "DataTo"
+	doc := FirestoreDocument{Fields: map[string]any{
+		"name":    map[string]any{"stringValue": "jane"},
+		"role":    map[string]any{"stringValue": "guest"},
+		"level":   map[string]any{"integerValue": "1"},
+		"tags":    map[string]any{"arrayValue": map[string]any{"values": []any{map[string]any{"stringValue": "a"}}}},
+		"address": map[string]any{"mapValue": map[string]any{"fields": map[string]any{"city": map[string]any{"stringValue": "Ghent"}}}},
+	}}
+
+	var user validateTestUser
+	if err := doc.DataTo(&user); err != nil {
+		t.Errorf("%v() without validation returned error: %v", thisFunctionName, err)
+	}
+
+	err := doc.DataTo(&user, WithValidation())
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 1 || verrs[0].Rule != "oneof" || verrs[0].Path.String() != "role" {
+		t.Errorf("%v() with validation returned %v", thisFunctionName, err)
+	}
+	if user.Name != "jane" {
+		t.Errorf("%v() with validation did not populate the target", thisFunctionName)
+	}
+}
+
+func TestDataToForeignValidateTags(t *testing.T) {
+	thisFunctionName := "DataTo"
+	type user struct {
+		Email string `firestore:"email" validate:"required,email"`
+	}
+	doc := FirestoreDocument{Fields: map[string]any{"email": map[string]any{"stringValue": "jane@example.com"}}}
+
+	var u user
+	if err := doc.DataTo(&u); err != nil {
+		t.Errorf("%v() with validate tags of another package returned error: %v", thisFunctionName, err)
+	}
+	if u.Email != "jane@example.com" {
+		t.Errorf("%v() output does not match expected data:\n%+v", thisFunctionName, u)
+	}
+	if err := doc.DataTo(&u, WithValidation()); err == nil {
+		t.Errorf("%v() with validation did not return an error for an unknown rule", thisFunctionName)
+	}
+}
@@ -0,0 +1,446 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// vectorTypeField and vectorType mark a Firestore map holding a vector embedding, whose "value" field is an array of doubles.
+const (
+	vectorTypeField = "__type__"
+	vectorType      = "__vector__"
+)
+
+// A Value is a typed Firestore value. Unlike unwrapped values, it records the Firestore data type of the value,
+// e.g. a ReferenceValue and a StringValue are both unwrapped to a string, and a NullValue is distinct from a missing field.
+//
+// Values are one of NullValue, BoolValue, IntegerValue, DoubleValue, TimestampValue, StringValue, BytesValue,
+// ReferenceValue, GeoPointValue, ArrayValue, MapValue and VectorValue.
+type Value interface {
+	// Type returns the Firestore data type of the value.
+	Type() FieldType
+	// Interface returns the value as unwrapped by ToMap, e.g. an int for an IntegerValue.
+	Interface() any
+
+	isValue()
+}
+
+// NullValue is a Firestore null value.
+type NullValue struct{}
+
+// BoolValue is a Firestore boolean value.
+type BoolValue bool
+
+// IntegerValue is a Firestore 64-bit signed integer value.
+type IntegerValue int64
+
+// DoubleValue is a Firestore 64-bit floating point value.
+type DoubleValue float64
+
+// TimestampValue is a Firestore timestamp value.
+type TimestampValue time.Time
+
+// StringValue is a Firestore string value.
+type StringValue string
+
+// BytesValue is a Firestore bytes value.
+type BytesValue []byte
+
+// ReferenceValue is a Firestore reference to a document, see Reference.
+type ReferenceValue string
+
+// GeoPointValue is a Firestore geographical point.
+type GeoPointValue struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// ArrayValue is a Firestore array value.
+// A nil ArrayValue is an array without values, which Firestore encodes without a "values" key.
+type ArrayValue []Value
+
+// MapValue is a Firestore map value, or the fields of a document.
+// A nil MapValue is a map without fields, which Firestore encodes without a "fields" key.
+type MapValue map[string]Value
+
+// VectorValue is a Firestore vector embedding, stored as a map with a "__type__" field set to "__vector__".
+type VectorValue []float64
+
+func (NullValue) Type() FieldType      { return TypeNull }
+func (BoolValue) Type() FieldType      { return TypeBoolean }
+func (IntegerValue) Type() FieldType   { return TypeInteger }
+func (DoubleValue) Type() FieldType    { return TypeDouble }
+func (TimestampValue) Type() FieldType { return TypeTimestamp }
+func (StringValue) Type() FieldType    { return TypeString }
+func (BytesValue) Type() FieldType     { return TypeBytes }
+func (ReferenceValue) Type() FieldType { return TypeReference }
+func (GeoPointValue) Type() FieldType  { return TypeGeoPoint }
+func (ArrayValue) Type() FieldType     { return TypeArray }
+func (MapValue) Type() FieldType       { return TypeMap }
+func (VectorValue) Type() FieldType    { return TypeVector }
+
+func (NullValue) Interface() any        { return nil }
+func (v BoolValue) Interface() any      { return bool(v) }
+func (v IntegerValue) Interface() any   { return int(v) }
+func (v DoubleValue) Interface() any    { return float64(v) }
+func (v TimestampValue) Interface() any { return time.Time(v) }
+func (v StringValue) Interface() any    { return string(v) }
+func (v BytesValue) Interface() any     { return []byte(v) }
+func (v ReferenceValue) Interface() any { return string(v) }
+
+func (v GeoPointValue) Interface() any {
+	return latlng.LatLng{Latitude: v.Latitude, Longitude: v.Longitude}
+}
+
+func (v ArrayValue) Interface() any {
+	if v == nil {
+		return []any(nil)
+	}
+	a := make([]any, len(v))
+	for i, x := range v {
+		a[i] = x.Interface()
+	}
+	return a
+}
+
+func (v MapValue) Interface() any {
+	return v.toMap()
+}
+
+// Interface returns the vector as unwrapped by ToMap, a map with a "__type__" field and a "value" array of float64.
+func (v VectorValue) Interface() any {
+	values := make([]any, len(v))
+	for i, f := range v {
+		values[i] = f
+	}
+	return map[string]any{vectorTypeField: vectorType, "value": values}
+}
+
+// toMap returns the unwrapped fields of the map, nil if it has none.
+func (v MapValue) toMap() map[string]any {
+	if v == nil {
+		return nil
+	}
+	m := make(map[string]any, len(v))
+	for k, x := range v {
+		m[k] = x.Interface()
+	}
+	return m
+}
+
+func (NullValue) isValue()      {}
+func (BoolValue) isValue()      {}
+func (IntegerValue) isValue()   {}
+func (DoubleValue) isValue()    {}
+func (TimestampValue) isValue() {}
+func (StringValue) isValue()    {}
+func (BytesValue) isValue()     {}
+func (ReferenceValue) isValue() {}
+func (GeoPointValue) isValue()  {}
+func (ArrayValue) isValue()     {}
+func (MapValue) isValue()       {}
+func (VectorValue) isValue()    {}
+
+// Values returns the document's fields as typed Values.
+func (d *FirestoreDocument) Values() (MapValue, error) {
+	if d == nil {
+		return nil, errors.New("nil document contents")
+	}
+	return parseFields(d.Fields, true)
+}
+
+// ParseFields parses Firestore protojson encoded fields, such as the Fields of a FirestoreDocument, into typed Values.
+func ParseFields(fields map[string]any) (MapValue, error) {
+	return parseFields(fields, false)
+}
+
+// parseFields parses fields, lenient accepts array elements that are bare maps of fields, see splitWrapped.
+func parseFields(fields map[string]any, lenient bool) (MapValue, error) {
+	if fields == nil {
+		return nil, errors.New("nil map contents")
+	}
+
+	m := make(MapValue, len(fields))
+	for k, val := range fields {
+		v, err := parseValue(val, false, lenient)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", quoteSegment(k), err)
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+// ParseValue parses a single Firestore protojson encoded value, such as {"stringValue": "foo"}, into a typed Value.
+// Maps holding a vector embedding are parsed into a VectorValue.
+func ParseValue(value any) (Value, error) {
+	return parseValue(value, false, false)
+}
+
+// parseValue parses a single value, elem is set for array elements and lenient is passed on to parseFields.
+func parseValue(value any, elem, lenient bool) (Value, error) {
+	tag, inner, err := splitWrapped(value, elem && lenient)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case protoNullTag:
+		if inner != nil && inner != "NULL_VALUE" {
+			return nil, fmt.Errorf("invalid Firestore null value: %v", inner)
+		}
+		return NullValue{}, nil
+
+	case protoBoolTag:
+		b, ok := inner.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid Firestore boolean value: %v", inner)
+		}
+		return BoolValue(b), nil
+
+	case protoIntTag:
+		if s, ok := inner.(string); ok {
+			// integers are encoded as strings as they may not fit in a JSON number
+			i, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid Firestore integer value: %v", err)
+			}
+			return IntegerValue(i), nil
+		}
+		i, err := unwrapInt(inner)
+		if err != nil {
+			return nil, err
+		}
+		return IntegerValue(i), nil
+
+	case protoDoubleTag:
+		f, err := unwrapDouble(inner)
+		if err != nil {
+			return nil, err
+		}
+		return DoubleValue(f), nil
+
+	case protoTimestampTag:
+		t, err := unwrapTimestamp(inner)
+		if err != nil {
+			return nil, err
+		}
+		return TimestampValue(t), nil
+
+	case protoStringTag:
+		s, ok := inner.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid Firestore string value: %v", inner)
+		}
+		return StringValue(s), nil
+
+	case protoBytesTag:
+		b, err := unwrapBytes(inner)
+		if err != nil {
+			return nil, err
+		}
+		return BytesValue(b), nil
+
+	case protoReferenceTag:
+		s, ok := inner.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid Firestore reference value: %v", inner)
+		}
+		return ReferenceValue(s), nil
+
+	case protoGeoPointTag:
+		gp, err := unwrapGeoPoint(inner)
+		if err != nil {
+			return nil, err
+		}
+		return GeoPointValue{Latitude: gp.Latitude, Longitude: gp.Longitude}, nil
+
+	case protoArrayTag:
+		a, ok := inner.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid Firestore array: %v", inner)
+		}
+		values, ok := a["values"]
+		if !ok {
+			if len(a) != 0 {
+				return nil, errors.New("invalid Firestore array, \"values\" key not found")
+			}
+			return ArrayValue(nil), nil
+		}
+		va, ok := values.([]any)
+		if !ok {
+			return nil, errors.New("invalid Firestore array, \"values\" does not contain an array of values")
+		}
+		arr := make(ArrayValue, len(va))
+		for i, x := range va {
+			v, err := parseValue(x, true, lenient)
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = v
+		}
+		return arr, nil
+	}
+
+	// protoMapTag
+	m, ok := inner.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid Firestore map: %v", inner)
+	}
+	fields, ok := m["fields"]
+	if !ok {
+		if len(m) != 0 {
+			return nil, errors.New("invalid Firestore map, \"fields\" key not found")
+		}
+		return MapValue(nil), nil
+	}
+	fm, ok := fields.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid Firestore map fields: %v", fields)
+	}
+	return parseMap(fm, lenient)
+}
+
+// parseMap parses the fields of a Firestore map, returning a VectorValue if the map holds a vector embedding.
+func parseMap(fields map[string]any, lenient bool) (Value, error) {
+	m, err := parseFields(fields, lenient)
+	if err != nil {
+		return nil, err
+	}
+	if vec, ok := vectorOf(m); ok {
+		return vec, nil
+	}
+	return m, nil
+}
+
+// vectorOf returns the vector embedding held by m, ok is false if m is not a vector.
+func vectorOf(m MapValue) (vec VectorValue, ok bool) {
+	if len(m) != 2 || m[vectorTypeField] != StringValue(vectorType) {
+		return nil, false
+	}
+	values, isArray := m["value"].(ArrayValue)
+	if !isArray {
+		return nil, false
+	}
+
+	vec = make(VectorValue, len(values))
+	for i, v := range values {
+		switch x := v.(type) {
+		case DoubleValue:
+			vec[i] = float64(x)
+		case IntegerValue:
+			vec[i] = float64(x)
+		default:
+			return nil, false
+		}
+	}
+	return vec, true
+}
+
+// WrapFields encodes typed Values into Firestore protojson encoded fields, the inverse of ParseFields.
+func WrapFields(m MapValue) map[string]any {
+	fields := make(map[string]any, len(m))
+	for k, v := range m {
+		fields[k] = WrapValue(v)
+	}
+	return fields
+}
+
+// WrapValue encodes a typed Value into a single Firestore protojson encoded value, the inverse of ParseValue.
+// Values are encoded as Firestore encodes them in JSON, e.g. integers, bytes, NaN and infinities as strings. A nil Value is
+// encoded as null, pointers to Values are encoded as the Value they point to.
+func WrapValue(v Value) map[string]any {
+	switch x := derefValue(v).(type) {
+	case nil, NullValue:
+		return map[string]any{protoNullTag: nil}
+	case BoolValue:
+		return map[string]any{protoBoolTag: bool(x)}
+	case IntegerValue:
+		return map[string]any{protoIntTag: strconv.FormatInt(int64(x), 10)}
+	case DoubleValue:
+		return map[string]any{protoDoubleTag: wrapDouble(float64(x))}
+	case TimestampValue:
+		return map[string]any{protoTimestampTag: formatTimestamp(time.Time(x))}
+	case StringValue:
+		return map[string]any{protoStringTag: string(x)}
+	case BytesValue:
+		return map[string]any{protoBytesTag: base64.StdEncoding.EncodeToString(x)}
+	case ReferenceValue:
+		return map[string]any{protoReferenceTag: string(x)}
+	case GeoPointValue:
+		return map[string]any{protoGeoPointTag: map[string]any{"latitude": x.Latitude, "longitude": x.Longitude}}
+
+	case ArrayValue:
+		if x == nil {
+			return map[string]any{protoArrayTag: map[string]any{}}
+		}
+		values := make([]any, len(x))
+		for i, elem := range x {
+			values[i] = WrapValue(elem)
+		}
+		return map[string]any{protoArrayTag: map[string]any{"values": values}}
+
+	case MapValue:
+		if x == nil {
+			return map[string]any{protoMapTag: map[string]any{}}
+		}
+		return map[string]any{protoMapTag: map[string]any{"fields": WrapFields(x)}}
+
+	case VectorValue:
+		values := make(ArrayValue, len(x))
+		for i, f := range x {
+			values[i] = DoubleValue(f)
+		}
+		return WrapValue(MapValue{vectorTypeField: StringValue(vectorType), "value": values})
+	}
+
+	// Value cannot be implemented outside of this package, and pointers were dereferenced
+	panic(fmt.Sprintf("WrapValue error, unknown Value type %T", v))
+}
+
+// derefValue returns the Value that v points to when v is a pointer, such as a *StringValue, which implements Value
+// through the methods of its element type. A nil pointer is returned as a nil Value.
+func derefValue(v Value) Value {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		return v
+	}
+	if rv.IsNil() {
+		return nil
+	}
+	return rv.Elem().Interface().(Value)
+}
+
+// wrapDouble returns f as protojson encodes doubles, NaN and infinities are strings since JSON cannot represent them.
+func wrapDouble(f float64) any {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return f
+}
@@ -0,0 +1,265 @@
+package firestruct
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bennovw/firestruct/internal/testutil"
+)
+
+func TestValuesInterface(t *testing.T) {
+	thisFunctionName := "ParseFields"
+	for i, test := range firestoreUnwrapTests {
+		if i == 10 {
+			// a field named mapValue holding an unwrapped map is not a valid Firestore value
+			continue
+		}
+		t.Run(test.Name, func(t *testing.T) {
+			values, err := ParseFields(testutil.WrapBareMaps(test.Input))
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+
+			testutil.IsDeepEqualTest(t, values.Interface(), test.Expected, thisFunctionName, test.Name)
+		})
+	}
+}
+
+func TestWrapFields(t *testing.T) {
+	thisFunctionName := "WrapFields"
+	doc := FirestoreDocument{Fields: testutil.TestFirebaseDocFields[12]}
+	values, err := doc.Values()
+	if err != nil {
+		t.Fatalf("%v() test \"%v\" returned error: %v", thisFunctionName, "Values", err)
+	}
+
+	result, err := ParseFields(WrapFields(values))
+	if err != nil {
+		t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, "round trip", err)
+	}
+	if !reflect.DeepEqual(result, values) {
+		t.Errorf("%v() test \"%v\" output does not match expected data:\n%v\n%v", thisFunctionName, "round trip", result, values)
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	thisFunctionName := "ParseValue"
+	created := time.Date(2023, 4, 14, 1, 2, 3, 400, time.UTC)
+	tests := []struct {
+		Name     string
+		Input    map[string]any
+		Expected Value
+		Wire     map[string]any
+	}{
+		{
+			Name:     "null",
+			Input:    map[string]any{"nullValue": nil},
+			Expected: NullValue{},
+			Wire:     map[string]any{"nullValue": nil},
+		},
+		{
+			Name:     "null enum",
+			Input:    map[string]any{"nullValue": "NULL_VALUE"},
+			Expected: NullValue{},
+			Wire:     map[string]any{"nullValue": nil},
+		},
+		{
+			Name:     "integer string",
+			Input:    map[string]any{"integerValue": "9007199254740993"},
+			Expected: IntegerValue(9007199254740993),
+			Wire:     map[string]any{"integerValue": "9007199254740993"},
+		},
+		{
+			Name:     "whole double",
+			Input:    map[string]any{"doubleValue": 2},
+			Expected: DoubleValue(2),
+			Wire:     map[string]any{"doubleValue": 2.0},
+		},
+		{
+			Name:     "infinity",
+			Input:    map[string]any{"doubleValue": "Infinity"},
+			Expected: DoubleValue(math.Inf(1)),
+			Wire:     map[string]any{"doubleValue": "Infinity"},
+		},
+		{
+			Name:     "negative infinity",
+			Input:    map[string]any{"doubleValue": "-Infinity"},
+			Expected: DoubleValue(math.Inf(-1)),
+			Wire:     map[string]any{"doubleValue": "-Infinity"},
+		},
+		{
+			Name:     "timestamp",
+			Input:    map[string]any{"timestampValue": "2023-04-14T01:02:03.0000004Z"},
+			Expected: TimestampValue(created),
+			Wire:     map[string]any{"timestampValue": "2023-04-14T01:02:03.0000004Z"},
+		},
+		{
+			Name:     "reference",
+			Input:    map[string]any{"referenceValue": "projects/p/databases/(default)/documents/users/jane"},
+			Expected: ReferenceValue("projects/p/databases/(default)/documents/users/jane"),
+			Wire:     map[string]any{"referenceValue": "projects/p/databases/(default)/documents/users/jane"},
+		},
+		{
+			Name:     "bytes",
+			Input:    map[string]any{"bytesValue": "SGVsbG8="},
+			Expected: BytesValue("Hello"),
+			Wire:     map[string]any{"bytesValue": "SGVsbG8="},
+		},
+		{
+			Name:     "geopoint",
+			Input:    map[string]any{"geoPointValue": map[string]any{"latitude": 51.2, "longitude": 3.2}},
+			Expected: GeoPointValue{Latitude: 51.2, Longitude: 3.2},
+			Wire:     map[string]any{"geoPointValue": map[string]any{"latitude": 51.2, "longitude": 3.2}},
+		},
+		{
+			Name:     "empty array",
+			Input:    map[string]any{"arrayValue": map[string]any{}},
+			Expected: ArrayValue(nil),
+			Wire:     map[string]any{"arrayValue": map[string]any{}},
+		},
+		{
+			Name:     "empty map",
+			Input:    map[string]any{"mapValue": map[string]any{"fields": map[string]any{}}},
+			Expected: MapValue{},
+			Wire:     map[string]any{"mapValue": map[string]any{"fields": map[string]any{}}},
+		},
+		{
+			Name: "vector",
+			Input: map[string]any{"mapValue": map[string]any{"fields": map[string]any{
+				"__type__": map[string]any{"stringValue": "__vector__"},
+				"value": map[string]any{"arrayValue": map[string]any{"values": []any{
+					map[string]any{"doubleValue": 0.5},
+					map[string]any{"integerValue": "1"},
+				}}},
+			}}},
+			Expected: VectorValue{0.5, 1},
+			Wire: map[string]any{"mapValue": map[string]any{"fields": map[string]any{
+				"__type__": map[string]any{"stringValue": "__vector__"},
+				"value": map[string]any{"arrayValue": map[string]any{"values": []any{
+					map[string]any{"doubleValue": 0.5},
+					map[string]any{"doubleValue": 1.0},
+				}}},
+			}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := ParseValue(test.Input)
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+			if !reflect.DeepEqual(result, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%#v\n%#v", thisFunctionName, test.Name, result, test.Expected)
+			}
+			if wire := WrapValue(result); !reflect.DeepEqual(wire, test.Wire) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%v\n%v", "WrapValue", test.Name, wire, test.Wire)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		Name  string
+		Input any
+	}{
+		{Name: "unwrapped", Input: "foo"},
+		{Name: "bare map", Input: map[string]any{"name": map[string]any{"stringValue": "a"}}},
+		{Name: "array of bare maps", Input: map[string]any{"arrayValue": map[string]any{"values": []any{
+			map[string]any{"name": map[string]any{"stringValue": "a"}},
+		}}}},
+		{Name: "two tags", Input: map[string]any{"stringValue": "a", "booleanValue": true}},
+		{Name: "wrong string type", Input: map[string]any{"stringValue": 1}},
+		{Name: "invalid integer", Input: map[string]any{"integerValue": "1.5"}},
+		{Name: "invalid null", Input: map[string]any{"nullValue": 0}},
+		{Name: "map without fields", Input: map[string]any{"mapValue": map[string]any{"values": []any{}}}},
+	}
+	for _, test := range errorTests {
+		t.Run(test.Name, func(t *testing.T) {
+			if _, err := ParseValue(test.Input); err == nil {
+				t.Errorf("%v() test \"%v\" expected an error", thisFunctionName, test.Name)
+			}
+		})
+	}
+}
+
+func TestWrapValuePointers(t *testing.T) {
+	thisFunctionName := "WrapValue"
+	str, null, array := StringValue("x"), NullValue{}, ArrayValue{BoolValue(true)}
+	var nilString *StringValue
+	tests := []struct {
+		Name     string
+		Input    Value
+		Expected map[string]any
+	}{
+		{Name: "string", Input: &str, Expected: map[string]any{"stringValue": "x"}},
+		{Name: "null", Input: &null, Expected: map[string]any{"nullValue": nil}},
+		{Name: "nil pointer", Input: nilString, Expected: map[string]any{"nullValue": nil}},
+		{Name: "array", Input: &array, Expected: map[string]any{"arrayValue": map[string]any{"values": []any{map[string]any{"booleanValue": true}}}}},
+		{Name: "nested", Input: ArrayValue{&str}, Expected: map[string]any{"arrayValue": map[string]any{"values": []any{map[string]any{"stringValue": "x"}}}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if result := WrapValue(test.Input); !reflect.DeepEqual(result, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%v\n%v", thisFunctionName, test.Name, result, test.Expected)
+			}
+		})
+	}
+
+	if size, expected := ValueSize(&str), ValueSize(str); size != expected {
+		t.Errorf("%v() of a pointer returned %d, expected %d", "ValueSize", size, expected)
+	}
+}
+
+func TestWrapValueJSONRoundTrip(t *testing.T) {
+	thisFunctionName := "WrapValue"
+	tests := []struct {
+		Name  string
+		Input Value
+	}{
+		{Name: "NaN", Input: DoubleValue(math.NaN())},
+		{Name: "infinity", Input: DoubleValue(math.Inf(1))},
+		{Name: "negative infinity", Input: DoubleValue(math.Inf(-1))},
+		{Name: "vector", Input: VectorValue{math.NaN(), math.Inf(1), math.Inf(-1)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			b, err := json.Marshal(WrapValue(test.Input))
+			if err != nil {
+				t.Fatalf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+			var wire map[string]any
+			if err := json.Unmarshal(b, &wire); err != nil {
+				t.Fatalf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+			result, err := ParseValue(wire)
+			if err != nil {
+				t.Fatalf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+			// NaN is not equal to itself, compare the formatted values instead
+			if fmt.Sprint(result) != fmt.Sprint(test.Input) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%v\n%v", thisFunctionName, test.Name, result, test.Input)
+			}
+		})
+	}
+}
+
+func TestValueTypes(t *testing.T) {
+	values := MapValue{
+		"ref":     ReferenceValue("projects/p/databases/(default)/documents/users/jane"),
+		"name":    StringValue("jane"),
+		"deleted": NullValue{},
+	}
+	m := values.Interface().(map[string]any)
+	if _, ok := m["deleted"]; !ok || m["deleted"] != nil {
+		t.Errorf("Interface() test \"null\" returned %v", m)
+	}
+	if values["ref"].Type() != TypeReference || values["name"].Type() != TypeString || m["ref"] != "projects/p/databases/(default)/documents/users/jane" {
+		t.Errorf("Type() test \"reference\" returned %v, %v", values["ref"].Type(), values["name"].Type())
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2023 Benno Van Waeyenberg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firestruct
+
+import (
+	"errors"
+)
+
+// SkipValue is returned by a WalkFunc or TransformFunc to skip the nested values of the current map or array.
+// A TransformFunc returning SkipValue keeps the current value unchanged.
+var SkipValue = errors.New("skip this value")
+
+// SkipAll is returned by a WalkFunc or TransformFunc to stop the traversal, Walk and Transform then return no error.
+// A TransformFunc returning SkipAll keeps the current value and all values not visited yet unchanged.
+var SkipAll = errors.New("skip all values")
+
+// A WalkFunc is called by Walk for every value of a document.
+// The fields of maps are visited with their path, and the elements of arrays share the path of the array.
+type WalkFunc func(path FieldPath, v Value) error
+
+// A TransformFunc is called by Transform for every value of a document, it returns the value to replace v with.
+// Returning v keeps the value, and returning a nil Value deletes the field or array element.
+type TransformFunc func(path FieldPath, v Value) (Value, error)
+
+// Walk calls fn for every value of the document, depth-first. Maps are visited before their fields, and fields in
+// lexicographical order. Arrays are visited before their elements, and elements in order.
+// Walk stops at the first error returned by fn, unless it is SkipValue or SkipAll.
+func Walk(doc *FirestoreDocument, fn WalkFunc) error {
+	values, err := doc.Values()
+	if err != nil {
+		return err
+	}
+	return WalkValues(values, fn)
+}
+
+// WalkValues calls fn for every value nested in values, in the same order as Walk.
+func WalkValues(values MapValue, fn WalkFunc) error {
+	if err := walkFields(nil, values, fn); err != nil && err != SkipAll {
+		return err
+	}
+	return nil
+}
+
+// walkFields walks the fields of a map nested at path.
+func walkFields(path FieldPath, m MapValue, fn WalkFunc) error {
+	for _, k := range sortedKeys(m) {
+		if err := walkValue(appendFieldPath(path, k), m[k], fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkValue calls fn for v, then walks the values nested in v.
+func walkValue(path FieldPath, v Value, fn WalkFunc) error {
+	if err := fn(path, v); err != nil {
+		if err == SkipValue {
+			return nil
+		}
+		return err
+	}
+
+	switch x := v.(type) {
+	case MapValue:
+		return walkFields(path, x, fn)
+	case ArrayValue:
+		for _, elem := range x {
+			if err := walkValue(path, elem, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Transform calls fn for every value of the document in the same order as Walk, and returns a new document with the values
+// returned by fn. The values nested in a replaced map or array are those of the replacement.
+// The document itself is not modified, the returned document shares its name and timestamps.
+func Transform(doc *FirestoreDocument, fn TransformFunc) (*FirestoreDocument, error) {
+	values, err := doc.Values()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &transformer{fn: fn}
+	transformed, err := t.fields(nil, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FirestoreDocument{
+		Name:       doc.Name,
+		Fields:     WrapFields(transformed),
+		CreateTime: doc.CreateTime,
+		UpdateTime: doc.UpdateTime,
+	}, nil
+}
+
+// transformer applies a TransformFunc to a tree of values.
+type transformer struct {
+	fn   TransformFunc
+	done bool // SkipAll was returned, remaining values are kept unchanged
+}
+
+// fields returns a new map with the transformed fields of m, deleted fields are left out.
+func (t *transformer) fields(path FieldPath, m MapValue) (MapValue, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	out := make(MapValue, len(m))
+	for _, k := range sortedKeys(m) {
+		v, err := t.value(appendFieldPath(path, k), m[k])
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			out[k] = v
+		}
+	}
+	return out, nil
+}
+
+// value returns the transformed value of v and its nested values, nil if it was deleted.
+func (t *transformer) value(path FieldPath, v Value) (Value, error) {
+	if t.done {
+		return v, nil
+	}
+
+	replaced, err := t.fn(path, v)
+	switch {
+	case err == SkipValue:
+		return v, nil
+	case err == SkipAll:
+		t.done = true
+		return v, nil
+	case err != nil:
+		return nil, err
+	}
+
+	switch x := replaced.(type) {
+	case MapValue:
+		return t.fields(path, x)
+	case ArrayValue:
+		if x == nil {
+			return x, nil
+		}
+		out := make(ArrayValue, 0, len(x))
+		for _, elem := range x {
+			e, err := t.value(path, elem)
+			if err != nil {
+				return nil, err
+			}
+			if e != nil {
+				out = append(out, e)
+			}
+		}
+		return out, nil
+	}
+	return replaced, nil
+}
@@ -0,0 +1,140 @@
+package firestruct
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bennovw/firestruct/internal/testutil"
+)
+
+func TestWalk(t *testing.T) {
+	thisFunctionName := "Walk"
+	doc := &FirestoreDocument{Fields: map[string]any{
+		"author": map[string]any{"referenceValue": "projects/p/databases/(default)/documents/users/jane"},
+		"title":  map[string]any{"stringValue": "Hello"},
+		"meta": map[string]any{"mapValue": map[string]any{"fields": map[string]any{
+			"editors": map[string]any{"arrayValue": map[string]any{"values": []any{
+				map[string]any{"referenceValue": "projects/p/databases/(default)/documents/users/joe"},
+				map[string]any{"mapValue": map[string]any{"fields": map[string]any{
+					"user": map[string]any{"referenceValue": "projects/p/databases/(default)/documents/users/ann"},
+				}}},
+			}}},
+		}}},
+	}}
+
+	tests := []struct {
+		Name     string
+		Skip     string
+		Err      error
+		Expected []string
+	}{
+		{
+			Name:     "all values",
+			Expected: []string{"author:reference", "meta:map", "meta.editors:array", "meta.editors:reference", "meta.editors:map", "meta.editors.user:reference", "title:string"},
+		},
+		{
+			Name:     "skip value",
+			Skip:     "meta.editors",
+			Err:      SkipValue,
+			Expected: []string{"author:reference", "meta:map", "meta.editors:array", "title:string"},
+		},
+		{
+			Name:     "skip all",
+			Skip:     "meta.editors",
+			Err:      SkipAll,
+			Expected: []string{"author:reference", "meta:map", "meta.editors:array"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var visited []string
+			err := Walk(doc, func(path FieldPath, v Value) error {
+				visited = append(visited, path.String()+":"+string(v.Type()))
+				if path.String() == test.Skip {
+					return test.Err
+				}
+				return nil
+			})
+			if err != nil {
+				t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, test.Name, err)
+			}
+			if !reflect.DeepEqual(visited, test.Expected) {
+				t.Errorf("%v() test \"%v\" output does not match expected data:\n%v\n%v", thisFunctionName, test.Name, visited, test.Expected)
+			}
+		})
+	}
+
+	errStop := errors.New("stop")
+	if err := Walk(doc, func(FieldPath, Value) error { return errStop }); err != errStop {
+		t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, "error", err)
+	}
+	if err := Walk(&FirestoreDocument{Fields: testutil.TestFirebaseDocFields[10]}, func(FieldPath, Value) error { return nil }); err == nil {
+		t.Errorf("%v() test \"%v\" expected an error", thisFunctionName, "invalid document")
+	}
+}
+
+func TestTransform(t *testing.T) {
+	thisFunctionName := "Transform"
+	doc := &FirestoreDocument{
+		Name: "projects/p/databases/(default)/documents/users/jane",
+		Fields: map[string]any{
+			"email":   map[string]any{"stringValue": "jane@example.com"},
+			"deleted": map[string]any{"nullValue": nil},
+			"contacts": map[string]any{"arrayValue": map[string]any{"values": []any{
+				map[string]any{"stringValue": "joe@example.com"},
+				map[string]any{"nullValue": nil},
+				map[string]any{"mapValue": map[string]any{"fields": map[string]any{
+					"email": map[string]any{"stringValue": "ann@example.com"},
+				}}},
+			}}},
+		},
+	}
+	original, _ := doc.ToMap()
+
+	result, err := Transform(doc, func(path FieldPath, v Value) (Value, error) {
+		switch x := v.(type) {
+		case NullValue:
+			return nil, nil
+		case StringValue:
+			if strings.Contains(string(x), "@") {
+				return StringValue("redacted"), nil
+			}
+		}
+		return v, nil
+	})
+	if err != nil {
+		t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, "redact", err)
+	}
+
+	m, err := result.ToMap()
+	if err != nil {
+		t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, "redact", err)
+	}
+	expected := map[string]any{
+		"email":    "redacted",
+		"contacts": []any{"redacted", map[string]any{"email": "redacted"}},
+	}
+	if !reflect.DeepEqual(m, expected) || result.Name != doc.Name {
+		t.Errorf("%v() test \"%v\" output does not match expected data:\n%v\n%v", thisFunctionName, "redact", m, expected)
+	}
+	if unchanged, _ := doc.ToMap(); !reflect.DeepEqual(unchanged, original) {
+		t.Errorf("%v() test \"%v\" modified the document: %v", thisFunctionName, "redact", unchanged)
+	}
+
+	// Values not visited after SkipAll are kept unchanged
+	result, err = Transform(doc, func(path FieldPath, v Value) (Value, error) {
+		if path.String() == "deleted" {
+			return nil, SkipAll
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Errorf("%v() test \"%v\" returned error: %v", thisFunctionName, "skip all", err)
+	}
+	if m, _ := result.ToMap(); !reflect.DeepEqual(m, map[string]any{"deleted": nil, "email": "jane@example.com"}) {
+		t.Errorf("%v() test \"%v\" output does not match expected data:\n%v", thisFunctionName, "skip all", m)
+	}
+}